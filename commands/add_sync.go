@@ -2,116 +2,170 @@ package commands
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"slices"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jackc/pgx"
 	"github.com/michaeldoylecs/discord-sync-bot/config"
 	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
 )
 
-var commandConfigAddSync = CommandConfig{
-	info: &discordgo.ApplicationCommand{
-		Name:        "add-sync",
+var subcommandConfigSyncAdd = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "add",
 		Description: "Sync a channel's messages with a given file URI's contents.",
 		Options: []*discordgo.ApplicationCommandOption{
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "file-uri",
-				Description: "File URI",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "file-uri",
+				Description:  "File URI. Leave out to set up the sync step by step.",
+				Required:     false,
+				Autocomplete: true,
 			},
+			channelOption(syncChannelTypes),
+			channelIdOption(false),
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "channel-id",
-				Description: "Channel ID",
-				Required:    true,
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "github-repo-url",
+				Description:  "GitHub repo URL",
+				Required:     false,
+				Autocomplete: true,
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "github-repo-url",
-				Description: "GitHub repo URL",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "suppress-embeds",
+				Description: "Hide link previews on the synced messages",
 				Required:    false,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "pin-messages",
+				Description: "Pin the synced messages",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "refresh-button",
+				Description: "Add a button to the last synced message that lets anyone refresh it",
+				Required:    false,
+			},
+			headingOption(),
+			attachmentModeOption(),
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleAddSyncCommand),
+		autocomplete: requireSyncPermission(autocompleteAddSync),
+		components: map[string]InteractionHandler{
+			addSyncWizardPrefix: requireSyncPermission(handleAddSyncWizard),
+		},
+		modals: map[string]InteractionHandler{
+			addSyncWizardModalID: requireSyncPermission(handleAddSyncWizard),
 		},
 	},
-	handler: func(discordSession *discordgo.Session, appCtx *config.AppCtx) {
-		discordSession.AddHandler(func(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-			if interaction.ApplicationCommandData().Name != "add-sync" {
-				return
-			}
+}
 
-			// Create logger with command relevant info
-			logger := newInteractionLogger(interaction.Interaction)
-			defer logExecutionTime(logger, "Command finished executing.")()
-			logger.Info().Msg("Command started.")
+func handleAddSyncCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
 
-			// Build options map
-			options := interaction.ApplicationCommandData().Options
-			optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
-			for _, opt := range options {
-				optionMap[opt.Name] = opt
-			}
+	// Without a file URI, walk the member through setting up the sync instead.
+	if _, ok := optionMap["file-uri"]; !ok {
+		err := sendAddSyncWizardModal(session, interaction)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+		}
+		return
+	}
+	fileUri := optionMap["file-uri"].StringValue()
 
-			fileUri := optionMap["file-uri"].StringValue()
-			channelId := optionMap["channel-id"].StringValue()
+	channel, msg, err := resolveChannelOption(session, interaction, optionMap, syncChannelTypes)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if channel == nil {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+	channelId := channel.ID
 
-			// Handle channel not existing within current guild.
-			channels, err := session.GuildChannels(interaction.GuildID)
-			if err != nil {
-				logger.Error().Err(err).Msg("")
-				sendErrorResponse(session, interaction.Interaction)
-				return
-			}
+	suppressEmbeds := false
+	if opt, ok := optionMap["suppress-embeds"]; ok {
+		suppressEmbeds = opt.BoolValue()
+	}
+	pinMessages := false
+	if opt, ok := optionMap["pin-messages"]; ok {
+		pinMessages = opt.BoolValue()
+	}
+	refreshButton := false
+	if opt, ok := optionMap["refresh-button"]; ok {
+		refreshButton = opt.BoolValue()
+	}
+	githubRepoUrl := ""
+	if opt, ok := optionMap["github-repo-url"]; ok {
+		githubRepoUrl = opt.StringValue()
+	}
+	heading := ""
+	if opt, ok := optionMap["heading"]; ok {
+		heading = opt.StringValue()
+	}
+	attachmentMode := attachmentModeAuto
+	if opt, ok := optionMap["attachment-mode"]; ok {
+		attachmentMode = opt.StringValue()
+	}
 
-			if !slices.ContainsFunc(channels, func(c *discordgo.Channel) bool {
-				return c.ID == channelId
-			}) {
-				msg := fmt.Sprintf("Channel: '%s' does not exist in this guild.", channelId)
-				sendEphemeralResponse(session, interaction.Interaction, msg)
-				return
-			}
+	// Add sync record to database
+	recordInfo := db.AddChannelSyncParams{
+		FileToSyncUri:           fileUri,
+		DiscordGuildSnowflake:   interaction.GuildID,
+		DiscordChannelSnowflake: channelId,
+		SuppressEmbeds:          suppressEmbeds,
+		PinMessages:             pinMessages,
+		RefreshButton:           refreshButton,
+		Heading:                 heading,
+		AttachmentMode:          attachmentMode,
+	}
+	syncRecord, err := addChannelSync(context.Background(), *appCtx, recordInfo, githubRepoUrl)
+	if err != nil {
+		logger.Error().Err(err).Interface("record_info", recordInfo).Msg("Failed to add sync record to database.")
+		sendErrorResponse(session, interaction)
+		return
+	}
 
-			// Add sync record to database
-			recordInfo := db.AddChannelSyncParams{
-				FileToSyncUri:           fileUri,
-				DiscordGuildSnowflake:   interaction.GuildID,
-				DiscordChannelSnowflake: channelId,
-			}
-			syncRecord, err := appCtx.DB.AddChannelSync(context.Background(), recordInfo)
-			if err != nil {
-				if errors.Is(err, pgx.ErrNoRows) {
-					logger.Error().Interface("record_info", recordInfo).Msg("Failed to add sync record to database.")
-				} else {
-					logger.Error().Err(err).Msg("")
-				}
-				sendErrorResponse(session, interaction.Interaction)
-				return
-			}
+	// Respond to command
+	msg = localize(interaction, "Added Sync Record.\n%s\n<#%s>", syncRecord.FileToSyncUri, syncRecord.DiscordChannelSnowflake)
+	sendEphemeralResponse(session, interaction, msg)
+}
 
-			// Associate file with GitHub repo if provided
-			if opt, ok := optionMap["github-repo-url"]; ok {
-				githubRepoUrl := opt.StringValue()
-				_, err = appCtx.DB.AddGithubRepoFile(context.Background(), db.AddGithubRepoFileParams{
-					GithubRepoUrl: githubRepoUrl,
-					FileToSyncFk:  syncRecord.ID,
-				})
-				if err != nil {
-					if errors.Is(err, pgx.ErrNoRows) {
-						logger.Error().Interface("record_info", recordInfo).Msg("Failed to add github repo record to database.")
-					} else {
-						logger.Error().Err(err).Msg("")
-					}
-					sendErrorResponse(session, interaction.Interaction)
-					return
-				}
-			}
+// Adds a file's sync record to a channel, or replaces it if the channel already syncs the file, associating it with a GitHub repo if githubRepoUrl is not empty.
+func addChannelSync(ctx context.Context, appCtx config.AppCtx, recordInfo db.AddChannelSyncParams, githubRepoUrl string) (db.FilesToSync, error) {
+	tx, err := appCtx.DBPool.Begin(ctx)
+	if err != nil {
+		return db.FilesToSync{}, err
+	}
+	defer tx.Rollback(ctx)
 
-			// Respond to command
-			msg := fmt.Sprintf("Added Sync Record.\n%s\n<#%s>", syncRecord.FileToSyncUri, syncRecord.DiscordChannelSnowflake)
-			sendEphemeralResponse(session, interaction.Interaction, msg)
+	queries := appCtx.DB.WithTx(tx)
+	syncRecord, err := queries.AddChannelSync(ctx, recordInfo)
+	if err != nil {
+		return db.FilesToSync{}, err
+	}
+
+	if githubRepoUrl != "" {
+		_, err = queries.AddGithubRepoFile(ctx, db.AddGithubRepoFileParams{
+			GithubRepoUrl: githubRepoUrl,
+			FileToSyncFk:  syncRecord.ID,
 		})
-	},
+		if err != nil {
+			return db.FilesToSync{}, err
+		}
+	}
+
+	return syncRecord, tx.Commit(ctx)
 }
@@ -0,0 +1,372 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Custom IDs are formatted as "add-sync-wizard:<action>:<wizard-id>", except for the modal's.
+	addSyncWizardPrefix  = "add-sync-wizard:"
+	addSyncWizardModalID = addSyncWizardPrefix + "modal"
+	// How long an unfinished wizard is kept, matching how long discord accepts responses to an interaction.
+	addSyncWizardTimeout = 15 * time.Minute
+)
+
+// Settings chosen so far in a /sync add wizard.
+// Custom IDs are too short to hold a URL, so wizards are kept in memory between interactions.
+type addSyncWizard struct {
+	userId         string
+	fileUri        string
+	githubRepoUrl  string
+	channelId      string
+	suppressEmbeds bool
+	pinMessages    bool
+	refreshButton  bool
+	createdAt      time.Time
+}
+
+var (
+	addSyncWizards   = make(map[string]addSyncWizard)
+	addSyncWizardsMu sync.Mutex
+)
+
+func saveAddSyncWizard(wizardId string, wizard addSyncWizard) {
+	addSyncWizardsMu.Lock()
+	defer addSyncWizardsMu.Unlock()
+
+	// Forget wizards that were abandoned part way through.
+	for id, w := range addSyncWizards {
+		if time.Since(w.createdAt) > addSyncWizardTimeout {
+			delete(addSyncWizards, id)
+		}
+	}
+	addSyncWizards[wizardId] = wizard
+}
+
+func getAddSyncWizard(wizardId string) (addSyncWizard, bool) {
+	addSyncWizardsMu.Lock()
+	defer addSyncWizardsMu.Unlock()
+	wizard, ok := addSyncWizards[wizardId]
+	if !ok || time.Since(wizard.createdAt) > addSyncWizardTimeout {
+		return addSyncWizard{}, false
+	}
+	return wizard, true
+}
+
+func deleteAddSyncWizard(wizardId string) {
+	addSyncWizardsMu.Lock()
+	defer addSyncWizardsMu.Unlock()
+	delete(addSyncWizards, wizardId)
+}
+
+// Starts the wizard by asking for the file URI and GitHub repo.
+func sendAddSyncWizardModal(session *discordgo.Session, interaction *discordgo.Interaction) error {
+	return session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: addSyncWizardModalID,
+			Title:    localize(interaction, "Add Sync"),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "file-uri",
+							Label:       localize(interaction, "File URI"),
+							Style:       discordgo.TextInputShort,
+							Placeholder: "https://raw.githubusercontent.com/...",
+							Required:    true,
+							MaxLength:   512,
+						},
+					},
+				},
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    "github-repo-url",
+							Label:       localize(interaction, "GitHub repo URL (optional)"),
+							Style:       discordgo.TextInputShort,
+							Placeholder: "https://github.com/...",
+							Required:    false,
+							MaxLength:   512,
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func handleAddSyncWizard(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	userId := interaction.Member.User.ID
+
+	if interaction.Type == discordgo.InteractionModalSubmit {
+		values := modalTextInputValues(interaction.ModalSubmitData())
+		wizard := addSyncWizard{
+			userId:        userId,
+			fileUri:       strings.TrimSpace(values["file-uri"]),
+			githubRepoUrl: strings.TrimSpace(values["github-repo-url"]),
+			createdAt:     time.Now(),
+		}
+		if wizard.fileUri == "" {
+			sendEphemeralResponse(session, interaction, localize(interaction, "A file URI is required."))
+			return
+		}
+
+		wizardId := uuid.New().String()
+		saveAddSyncWizard(wizardId, wizard)
+
+		data := addSyncWizardSettings(interaction, wizardId, wizard)
+		data.Flags = discordgo.MessageFlagsEphemeral
+		err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+		}
+		return
+	}
+
+	data := interaction.MessageComponentData()
+	action, wizardId, _ := strings.Cut(strings.TrimPrefix(data.CustomID, addSyncWizardPrefix), ":")
+	wizard, ok := getAddSyncWizard(wizardId)
+	if !ok || wizard.userId != userId {
+		sendUpdateMessageResponse(session, interaction, localize(interaction, "This sync setup has expired. Run /sync add again."))
+		return
+	}
+
+	switch action {
+	case "channel":
+		if len(data.Values) > 0 {
+			wizard.channelId = data.Values[0]
+		}
+	case "pin":
+		wizard.pinMessages = !wizard.pinMessages
+	case "embeds":
+		wizard.suppressEmbeds = !wizard.suppressEmbeds
+	case "refresh":
+		wizard.refreshButton = !wizard.refreshButton
+	case "back":
+		// Return to the settings from the preview.
+	case "cancel":
+		deleteAddSyncWizard(wizardId)
+		sendUpdateMessageResponse(session, interaction, localize(interaction, "Sync setup cancelled."))
+		return
+	case "preview":
+		handleAddSyncWizardPreview(session, interaction, wizardId, wizard, logger)
+		return
+	case "confirm":
+		recordInfo := db.AddChannelSyncParams{
+			FileToSyncUri:           wizard.fileUri,
+			DiscordGuildSnowflake:   interaction.GuildID,
+			DiscordChannelSnowflake: wizard.channelId,
+			SuppressEmbeds:          wizard.suppressEmbeds,
+			PinMessages:             wizard.pinMessages,
+			RefreshButton:           wizard.refreshButton,
+			AttachmentMode:          attachmentModeAuto,
+		}
+		syncRecord, err := addChannelSync(context.Background(), *appCtx, recordInfo, wizard.githubRepoUrl)
+		if err != nil {
+			logger.Error().Err(err).Interface("record_info", recordInfo).Msg("Failed to add sync record to database.")
+			sendErrorResponse(session, interaction)
+			return
+		}
+		deleteAddSyncWizard(wizardId)
+
+		msg := localize(interaction, "Added Sync Record.\n%s\n<#%s>\nRun /sync run to post the file.", syncRecord.FileToSyncUri, syncRecord.DiscordChannelSnowflake)
+		sendUpdateMessageResponse(session, interaction, msg)
+		return
+	default:
+		logger.Error().Str("custom_id", data.CustomID).Msg("Malformed component custom ID.")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	saveAddSyncWizard(wizardId, wizard)
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: addSyncWizardSettings(interaction, wizardId, wizard),
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+// Fetches the file and shows its first chunk as it would be posted, with buttons to confirm or go back.
+func handleAddSyncWizardPreview(session *discordgo.Session, interaction *discordgo.Interaction, wizardId string, wizard addSyncWizard, logger zerolog.Logger) {
+	// Fetching the file can take longer than the interaction deadline.
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	fileContents, _, err := fetchFileContents(logger.WithContext(context.Background()), wizard.fileUri)
+	if err != nil {
+		settings := addSyncWizardSettings(interaction, wizardId, wizard)
+		content := truncateMessage(localize(interaction, "Failed to fetch %s: %s\n\n%s", wizard.fileUri, err, settings.Content))
+		session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+			Content:    &content,
+			Components: &settings.Components,
+		})
+		return
+	}
+
+	// Syncs set up step by step are in auto mode, so large files are posted as an attachment.
+	chunks, _, attached := fileMessageContents(db.FilesToSync{
+		FileToSyncUri:  wizard.fileUri,
+		AttachmentMode: attachmentModeAuto,
+	}, fileContents)
+	preview := localize(interaction, "The file is empty.")
+	if len(chunks) > 0 {
+		preview = chunks[0]
+	}
+	content := localize(interaction, "Preview of the first of %d messages to be posted in <#%s>.", len(chunks), wizard.channelId)
+	if attached {
+		content = localize(interaction, "The file is over %d KB, so it will be posted in <#%s> as an attachment under this message.", attachmentModeThreshold/1024, wizard.channelId)
+	}
+	embeds := []*discordgo.MessageEmbed{{Description: preview}}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    localize(interaction, "Confirm"),
+					Style:    discordgo.SuccessButton,
+					CustomID: addSyncWizardPrefix + "confirm:" + wizardId,
+				},
+				discordgo.Button{
+					Label:    localize(interaction, "Back"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: addSyncWizardPrefix + "back:" + wizardId,
+				},
+				discordgo.Button{
+					Label:    localize(interaction, "Cancel"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: addSyncWizardPrefix + "cancel:" + wizardId,
+				},
+			},
+		},
+	}
+	_, err = session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+// Builds the wizard's settings step: a channel picker, option toggles, and buttons to preview or cancel.
+func addSyncWizardSettings(interaction *discordgo.Interaction, wizardId string, wizard addSyncWizard) *discordgo.InteractionResponseData {
+	githubRepo := localize(interaction, "None")
+	if wizard.githubRepoUrl != "" {
+		githubRepo = wizard.githubRepoUrl
+	}
+	channel := localize(interaction, "Not chosen")
+	var defaultChannels []discordgo.SelectMenuDefaultValue
+	if wizard.channelId != "" {
+		channel = fmt.Sprintf("<#%s>", wizard.channelId)
+		defaultChannels = []discordgo.SelectMenuDefaultValue{
+			{ID: wizard.channelId, Type: discordgo.SelectMenuDefaultValueChannel},
+		}
+	}
+
+	pinLabel, pinStyle := localize(interaction, "Pin messages: Off"), discordgo.SecondaryButton
+	if wizard.pinMessages {
+		pinLabel, pinStyle = localize(interaction, "Pin messages: On"), discordgo.PrimaryButton
+	}
+	embedsLabel, embedsStyle := localize(interaction, "Link previews: Shown"), discordgo.SecondaryButton
+	if wizard.suppressEmbeds {
+		embedsLabel, embedsStyle = localize(interaction, "Link previews: Hidden"), discordgo.PrimaryButton
+	}
+	refreshLabel, refreshStyle := localize(interaction, "Refresh button: Off"), discordgo.SecondaryButton
+	if wizard.refreshButton {
+		refreshLabel, refreshStyle = localize(interaction, "Refresh button: On"), discordgo.PrimaryButton
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: truncateMessage(localize(interaction,
+			"Source: %s\nGitHub repo: %s\nChannel: %s\nPick a channel to sync to, then preview the first message.",
+			wizard.fileUri, githubRepo, channel,
+		)),
+		// Clear the preview when coming back from it.
+		Embeds: []*discordgo.MessageEmbed{},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						MenuType:      discordgo.ChannelSelectMenu,
+						CustomID:      addSyncWizardPrefix + "channel:" + wizardId,
+						Placeholder:   localize(interaction, "Channel to sync to"),
+						ChannelTypes:  syncChannelTypes,
+						DefaultValues: defaultChannels,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    pinLabel,
+						Style:    pinStyle,
+						CustomID: addSyncWizardPrefix + "pin:" + wizardId,
+					},
+					discordgo.Button{
+						Label:    embedsLabel,
+						Style:    embedsStyle,
+						CustomID: addSyncWizardPrefix + "embeds:" + wizardId,
+					},
+					discordgo.Button{
+						Label:    refreshLabel,
+						Style:    refreshStyle,
+						CustomID: addSyncWizardPrefix + "refresh:" + wizardId,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    localize(interaction, "Preview"),
+						Style:    discordgo.PrimaryButton,
+						CustomID: addSyncWizardPrefix + "preview:" + wizardId,
+						Disabled: wizard.channelId == "",
+					},
+					discordgo.Button{
+						Label:    localize(interaction, "Cancel"),
+						Style:    discordgo.SecondaryButton,
+						CustomID: addSyncWizardPrefix + "cancel:" + wizardId,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Gets the values of a modal's text inputs by custom ID.
+func modalTextInputValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := make(map[string]string)
+	for _, row := range data.Components {
+		actionsRow, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actionsRow.Components {
+			if input, ok := component.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+	return values
+}
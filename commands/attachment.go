@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+)
+
+// How a sync posts its file, stored on the sync record.
+const (
+	// Posts the file as messages until it is larger than attachmentModeThreshold, then as an attachment.
+	attachmentModeAuto = "auto"
+	// Always posts the file as messages, however many it takes.
+	attachmentModeMessages = "messages"
+	// Always posts the file as an attachment under a summary message.
+	attachmentModeAttachment = "attachment"
+)
+
+// Size in bytes above which syncs in auto mode post their file as an attachment. A file this large
+// takes at least 20 messages, which floods the channel and is slow to sync.
+const attachmentModeThreshold = 40 * 1024
+
+// Reports whether a sync in the given mode posts fileContents as an attachment rather than as messages.
+// Empty files are never attached, since they have nothing to post.
+func usesAttachment(attachmentMode string, fileContents string) bool {
+	if strings.TrimSpace(fileContents) == "" {
+		return false
+	}
+	switch attachmentMode {
+	case attachmentModeAttachment:
+		return true
+	case attachmentModeMessages:
+		return false
+	default:
+		return len(fileContents) > attachmentModeThreshold
+	}
+}
+
+// Gets the content of each of the sync's messages for fileContents along with the hash each is stored with,
+// and whether the file is attached to the only message rather than split across them.
+func fileMessageContents(fileToSync db.FilesToSync, fileContents string) ([]string, []string, bool) {
+	attached := usesAttachment(fileToSync.AttachmentMode, fileContents)
+	var chunks []string
+	if attached {
+		chunks = []string{attachmentSummary(fileToSync.FileToSyncUri, fileContents)}
+	} else {
+		chunks = chunkContents(fileContents, maxChunkLength)
+	}
+
+	contents := make([]string, len(chunks))
+	hashes := make([]string, len(chunks))
+	for i := range chunks {
+		contents[i] = chunkMessageContent(fileToSync, chunks, i)
+		hashes[i] = hashContents(contents[i])
+	}
+	// The attachment isn't part of the message's content, so the file is hashed along with it
+	// to have the message edited when only the file changes.
+	if attached {
+		hashes[0] = hashContents(contents[0] + "\n" + fileContents)
+	}
+	return contents, hashes, attached
+}
+
+// Describes an attached file in the message it is attached to.
+func attachmentSummary(fileUrl string, fileContents string) string {
+	return fmt.Sprintf("📎 `%s` · %d lines · %.1f KB", attachmentFileName(fileUrl), countLines(fileContents), float64(len(fileContents))/1024)
+}
+
+// Counts the lines of a file, not counting the empty line after a trailing newline.
+func countLines(fileContents string) int {
+	return strings.Count(strings.TrimRight(fileContents, "\n"), "\n") + 1
+}
+
+// Gets the file to upload with a sync's message, or nil if the sync's file is posted as messages.
+// Each call returns a new reader, since sending a file consumes it.
+func attachmentFiles(fileToSync db.FilesToSync, fileContents string, attached bool) []*discordgo.File {
+	if !attached {
+		return nil
+	}
+	return []*discordgo.File{
+		{
+			Name:        attachmentFileName(fileToSync.FileToSyncUri),
+			ContentType: "text/plain; charset=utf-8",
+			Reader:      strings.NewReader(fileContents),
+		},
+	}
+}
+
+// Names an attachment after the synced file. Files without an extension are named as text,
+// so discord shows a preview of them.
+func attachmentFileName(fileUrl string) string {
+	name := "file"
+	if parsedUrl, err := url.Parse(fileUrl); err == nil && path.Base(parsedUrl.Path) != "/" && path.Base(parsedUrl.Path) != "." {
+		name = path.Base(parsedUrl.Path)
+	}
+	if path.Ext(name) == "" {
+		name += ".txt"
+	}
+	return name
+}
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Maximum number of choices discord accepts in an autocomplete response.
+	maxAutocompleteChoices = 25
+	// Maximum length of an autocomplete choice's name and value.
+	maxAutocompleteChoiceLength = 100
+)
+
+// Suggests channels in the guild that already have a sync for the "channel-id" option,
+// and the given channel's synced files for the "file" option.
+func autocompleteSyncedChannels(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	focused := getFocusedOption(interaction)
+	if focused != nil && focused.Name == "file" {
+		autocompleteSyncedFiles(session, interaction, appCtx, logger)
+		return
+	}
+	if focused == nil || focused.Name != "channel-id" {
+		sendAutocompleteResponse(session, interaction, nil)
+		return
+	}
+	search := strings.ToLower(focused.StringValue())
+
+	fileSyncs, err := appCtx.DB.GetGuildSyncs(context.Background(), interaction.GuildID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendAutocompleteResponse(session, interaction, nil)
+		return
+	}
+
+	// Channel names are nicer to pick from than snowflakes, but fall back to the snowflake if they can't be fetched.
+	channelNames := make(map[string]string)
+	channels, err := session.GuildChannels(interaction.GuildID)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to get guild channel names.")
+	}
+	for _, channel := range channels {
+		channelNames[channel.ID] = channel.Name
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	seen := make(map[string]bool)
+	for _, fileSync := range fileSyncs {
+		// A channel with several files has a sync for each of them.
+		channelId := fileSync.DiscordChannelSnowflake
+		if seen[channelId] {
+			continue
+		}
+		seen[channelId] = true
+		name := channelId
+		if channelName, ok := channelNames[channelId]; ok {
+			name = "#" + channelName + " (" + channelId + ")"
+		}
+		if !strings.Contains(strings.ToLower(name), search) {
+			continue
+		}
+
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateChoiceName(name),
+			Value: channelId,
+		})
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+	}
+
+	sendAutocompleteResponse(session, interaction, choices)
+}
+
+// Suggests the files synced to the channel given by the "channel" or "channel-id" option.
+func autocompleteSyncedFiles(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+	channelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		sendAutocompleteResponse(session, interaction, nil)
+		return
+	}
+	search := strings.ToLower(optionMap["file"].StringValue())
+
+	channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(context.Background(), db.GetGuildChannelSyncsParams{
+		GuildID:   interaction.GuildID,
+		ChannelID: channelId,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendAutocompleteResponse(session, interaction, nil)
+		return
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	for _, channelSync := range channelSyncs {
+		if !strings.Contains(strings.ToLower(channelSync.FileToSyncUri), search) {
+			continue
+		}
+		// The URI may be too long to be a choice's value, so the sync's ID is used instead.
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  truncateChoiceName(channelSync.FileToSyncUri),
+			Value: strconv.FormatInt(channelSync.ID, 10),
+		})
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+	}
+
+	sendAutocompleteResponse(session, interaction, choices)
+}
+
+// Suggests source URLs and GitHub repos already used in the guild for /sync add.
+func autocompleteAddSync(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	focused := getFocusedOption(interaction)
+	if focused == nil {
+		sendAutocompleteResponse(session, interaction, nil)
+		return
+	}
+
+	var urls []string
+	var err error
+	switch focused.Name {
+	case "file-uri":
+		urls, err = appCtx.DB.GetGuildSyncUrls(context.Background(), db.GetGuildSyncUrlsParams{
+			GuildID:    interaction.GuildID,
+			Search:     focused.StringValue(),
+			MaxResults: maxAutocompleteChoices,
+		})
+	case "github-repo-url":
+		urls, err = appCtx.DB.GetGuildGithubRepoUrls(context.Background(), db.GetGuildGithubRepoUrlsParams{
+			GuildID:    interaction.GuildID,
+			Search:     focused.StringValue(),
+			MaxResults: maxAutocompleteChoices,
+		})
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(urls))
+	for _, url := range urls {
+		// Discord rejects choice values that are too long, and a truncated URL is no use.
+		if len(url) > maxAutocompleteChoiceLength {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  url,
+			Value: url,
+		})
+	}
+
+	sendAutocompleteResponse(session, interaction, choices)
+}
+
+// Suggests synced channels and files for the "channel-id" and "file" options, and source URLs and GitHub repos for the others.
+func autocompleteEditSync(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	if focused := getFocusedOption(interaction); focused != nil && (focused.Name == "channel-id" || focused.Name == "file") {
+		autocompleteSyncedChannels(session, interaction, appCtx, logger)
+		return
+	}
+	autocompleteAddSync(session, interaction, appCtx, logger)
+}
+
+func getFocusedOption(interaction *discordgo.Interaction) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range commandOptions(interaction) {
+		if opt.Focused {
+			return opt
+		}
+	}
+	return nil
+}
+
+func truncateChoiceName(name string) string {
+	if runes := []rune(name); len(runes) > maxAutocompleteChoiceLength {
+		return string(runes[:maxAutocompleteChoiceLength])
+	}
+	return name
+}
+
+func sendAutocompleteResponse(session *discordgo.Session, interaction *discordgo.Interaction, choices []*discordgo.ApplicationCommandOptionChoice) {
+	if choices == nil {
+		choices = []*discordgo.ApplicationCommandOptionChoice{}
+	}
+	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Maximum length in bytes of a file chunk. Discord allows 2000 characters per message, and a character
+// is never shorter than a byte, so this leaves room for a file's heading above its first chunk.
+const maxChunkLength = 1950
+
+const (
+	// Chunks end at anchors: blocks or lines picked by a hash of their text, with a chance proportional to
+	// their size. Past its minimum size, a chunk ends at an anchor about every this fraction of the maximum.
+	chunkAnchorSpacingDivisor = 2
+	// A chunk doesn't end at an anchor until it is this fraction of the maximum size. Keeping the minimum small
+	// lets the chunks after an edit soon end at the same anchors as before it, whatever the edit did to their size.
+	minChunkSizeDivisor = 8
+)
+
+var (
+	markdownHeading = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	// Captures a code fence's run of backticks or tildes, followed by its info string such as a language tag.
+	markdownFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+)
+
+// A piece of a file small enough to be posted as one message.
+type contentChunk struct {
+	// Markdown to post, including any code fence closed at its end or reopened at its start.
+	text string
+	// Lines of the file the chunk was cut from, numbered from 1.
+	startLine int
+	endLine   int
+}
+
+// A line of a file, along with the code block it is part of, if any.
+type chunkLine struct {
+	text   string
+	number int
+	// Opening line of the code block the line is part of, including the block's opening and closing lines.
+	fence string
+	// Whether the code block is still open after the line.
+	fenceOpen bool
+}
+
+// Lines that are kept in the same chunk when possible, such as a paragraph or a code block.
+type chunkBlock struct {
+	lines     []chunkLine
+	isHeading bool
+}
+
+// Splits contents into chunks of at most maxChunkSize bytes.
+func chunkContents(contents string, maxChunkSize int) []string {
+	chunks := chunkMarkdown(contents, maxChunkSize)
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.text
+	}
+	return texts
+}
+
+// Splits markdown into chunks of at most maxChunkSize bytes, without breaking its formatting.
+// Chunks end at headings or blank lines where possible, and otherwise at the end of a line.
+// Where they end is anchored to the content, so an edit to the markdown only changes the chunks around it.
+// Code blocks split across chunks are closed at the end of one and reopened, with their language tag, at the start of the next.
+// Lines too long for a chunk of their own are wrapped at a space, or failing that between characters.
+func chunkMarkdown(contents string, maxChunkSize int) []contentChunk {
+	chunks := make([]contentChunk, 0, len(contents)/maxChunkSize+1)
+	if contents == "" {
+		return chunks
+	}
+
+	var current []chunkLine
+	flush := func(lines []chunkLine) {
+		if len(lines) == 0 {
+			return
+		}
+		text := renderChunkLines(lines)
+		// Discord rejects messages that are only whitespace.
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		// Trailing blank lines don't show in discord, so they aren't counted as covered.
+		last := len(lines) - 1
+		for last > 0 && strings.TrimSpace(lines[last].text) == "" {
+			last--
+		}
+		chunks = append(chunks, contentChunk{
+			text:      text,
+			startLine: lines[0].number,
+			endLine:   lines[last].number,
+		})
+	}
+	fits := func(lines []chunkLine, more []chunkLine) bool {
+		return len(renderChunkLines(append(lines[:len(lines):len(lines)], more...))) <= maxChunkSize
+	}
+
+	// Chunks are cut at anchors once they are big enough, so the boundaries depend on the content around them
+	// rather than on everything before. An edit then only changes the chunks near it, as the chunks after it
+	// are cut at the same anchors as before.
+	minChunkSize := maxChunkSize / minChunkSizeDivisor
+	anchorSpacing := maxChunkSize / chunkAnchorSpacingDivisor
+	for _, block := range splitChunkBlocks(contents, maxChunkSize) {
+		blockSize := len(renderChunkLines(block.lines))
+		if len(current) > 0 && isChunkAnchor(block.lines[0], block.isHeading, blockSize, anchorSpacing) && len(renderChunkLines(current)) >= minChunkSize {
+			flush(current)
+			current = nil
+		}
+		if fits(current, block.lines) {
+			current = append(current, block.lines...)
+			continue
+		}
+
+		flush(current)
+		current = nil
+		if fits(current, block.lines) {
+			current = append(current, block.lines...)
+			continue
+		}
+
+		// The block is too big for any chunk, so fill chunks with it line by line.
+		for _, line := range block.lines {
+			if len(current) > 0 && isChunkAnchor(line, false, len(line.text), anchorSpacing) && len(renderChunkLines(current)) >= minChunkSize {
+				flush(current)
+				current = nil
+			}
+			if fits(current, []chunkLine{line}) {
+				current = append(current, line)
+				continue
+			}
+			// A chunk holding only a code block's opening line would post an empty code block. The block is
+			// reopened at the start of the next chunk anyway, so the opening line is left to that.
+			if len(current) != 1 || current[0].text != current[0].fence {
+				flush(current)
+			}
+			current = nil
+			if fits(current, []chunkLine{line}) {
+				current = append(current, line)
+				continue
+			}
+
+			// Each piece of a wrapped line gets a chunk of its own, so no line breaks are added between them.
+			pieces := wrapChunkLine(line, maxChunkSize)
+			for _, piece := range pieces[:len(pieces)-1] {
+				flush([]chunkLine{piece})
+			}
+			current = pieces[len(pieces)-1:]
+		}
+	}
+	flush(current)
+
+	return chunks
+}
+
+// Reports whether a chunk may end before the line, which starts size bytes of content. Lines are picked by a hash
+// of their text, so the same lines are picked however the lines around them change, with a chance of size in
+// spacing so that anchors come about every spacing bytes however long the lines are. Headings always are anchors.
+func isChunkAnchor(line chunkLine, isHeading bool, size int, spacing int) bool {
+	if isHeading {
+		return true
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(line.text))
+	return int(hash.Sum32()%uint32(spacing)) <= size
+}
+
+// Splits contents into blocks of lines: code blocks, headings, and paragraphs, each followed by any blank lines after it.
+// A code block whose opening line is too long to repeat in every chunk it spans is reopened with just its backticks
+// or tildes, and its opening line is kept as text inside the block.
+func splitChunkBlocks(contents string, maxChunkSize int) []chunkBlock {
+	blocks := make([]chunkBlock, 0)
+	var current *chunkBlock
+	startBlock := func(isHeading bool) {
+		blocks = append(blocks, chunkBlock{isHeading: isHeading})
+		current = &blocks[len(blocks)-1]
+	}
+
+	fence := ""
+	for i, text := range strings.Split(contents, "\n") {
+		line := chunkLine{text: text, number: i + 1}
+
+		switch {
+		case fence != "":
+			// Inside a code block, only its closing fence matters.
+			line.fence = fence
+			if isClosingFence(text, fenceMarker(fence)) {
+				fence = ""
+			}
+		case markdownFence.MatchString(text):
+			startBlock(false)
+			fence = text
+			if len(fence) > maxChunkSize/2 {
+				fence = fenceMarker(fence)
+			}
+			line.fence = fence
+		case strings.TrimSpace(text) == "":
+			// Blank lines end a block but stay with it, so chunks don't start with them.
+			if len(blocks) == 0 {
+				startBlock(false)
+			}
+			blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, line)
+			current = nil
+			continue
+		case markdownHeading.MatchString(text):
+			startBlock(true)
+		case current == nil:
+			startBlock(false)
+		}
+
+		line.fenceOpen = fence != ""
+		current.lines = append(current.lines, line)
+	}
+
+	return blocks
+}
+
+// Gets the run of backticks or tildes that opens a code block.
+func fenceMarker(fence string) string {
+	return markdownFence.FindStringSubmatch(fence)[1]
+}
+
+// Reports whether a line closes a code block opened with marker. The closing run must use the same
+// character and be at least as long, with nothing after it.
+func isClosingFence(text string, marker string) bool {
+	trimmed := strings.TrimSpace(text)
+	indent := len(text) - len(strings.TrimLeft(text, " "))
+	return indent <= 3 && len(trimmed) >= len(marker) && strings.Trim(trimmed, marker[:1]) == ""
+}
+
+// Joins lines into the text of a chunk, closing a code block left open at the end and reopening one open at the start.
+func renderChunkLines(lines []chunkLine) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	// The opening line of a code block already opens it.
+	if first := lines[0]; first.fence != "" && first.fence != first.text {
+		sb.WriteString(first.fence + "\n")
+	}
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(line.text)
+	}
+	if last := lines[len(lines)-1]; last.fenceOpen {
+		sb.WriteString("\n" + fenceMarker(last.fence))
+	}
+	return sb.String()
+}
+
+// Splits a line too long to fit in a chunk into pieces that do, leaving room to close and reopen its code block.
+// Lines are wrapped at the last space that fits, or between characters if there is none.
+func wrapChunkLine(line chunkLine, maxChunkSize int) []chunkLine {
+	limit := maxChunkSize
+	if line.fence != "" {
+		limit -= len(line.fence) + len(fenceMarker(line.fence)) + 2
+	}
+	if limit < utf8.UTFMax {
+		limit = utf8.UTFMax
+	}
+
+	pieces := make([]chunkLine, 0, len(line.text)/limit+1)
+	text := line.text
+	for len(text) > limit {
+		cut := limit
+		// Step back to the start of a character, so none are split between pieces.
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if space := strings.LastIndexAny(text[:cut], " \t"); space > limit/2 {
+			cut = space + 1
+		}
+		piece := line
+		piece.text = text[:cut]
+		pieces = append(pieces, piece)
+		text = text[cut:]
+	}
+	line.text = text
+	return append(pieces, line)
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Chunks contents, failing the test if any chunk is too long, is not valid UTF-8, or is only whitespace.
+func chunkAndCheck(t *testing.T, contents string) []string {
+	t.Helper()
+	chunks := chunkContents(contents, maxChunkLength)
+	for i, chunk := range chunks {
+		if len(chunk) > maxChunkLength {
+			t.Errorf("chunk %d is %d bytes, over the %d byte limit", i+1, len(chunk), maxChunkLength)
+		}
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %d is not valid UTF-8", i+1)
+		}
+		if strings.TrimSpace(chunk) == "" {
+			t.Errorf("chunk %d is only whitespace", i+1)
+		}
+	}
+	return chunks
+}
+
+// Joins the lines of a code block, numbered so that no two are the same.
+func codeLines(count int, format string) string {
+	lines := make([]string, count)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(format, i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestChunkContentsReopensSplitCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		fence   string
+		closing string
+	}{
+		{"backticks with language", "```go", "```"},
+		{"tildes", "~~~python", "~~~"},
+		{"longer run of backticks", "````md", "````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := tt.fence + "\n" + codeLines(300, "fmt.Println(%d) // padding to fill the chunks") + "\n" + tt.closing
+			chunks := chunkAndCheck(t, contents)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want the code block split across several", len(chunks))
+			}
+			for i, chunk := range chunks {
+				if !strings.HasPrefix(chunk, tt.fence+"\n") {
+					t.Errorf("chunk %d starts with %q, want it to reopen %q", i+1, chunk[:len(tt.fence)], tt.fence)
+				}
+				if !strings.HasSuffix(chunk, "\n"+tt.closing) {
+					t.Errorf("chunk %d does not close the code block with %q", i+1, tt.closing)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkContentsKeepsShorterFencesInsideCodeBlocks(t *testing.T) {
+	// A run of three backticks can't close a block opened with four, so the block continues past it.
+	inner := "```\nnested\n```\n"
+	contents := "````md\n" + strings.Repeat(inner, 200) + "````\nafter"
+	chunks := chunkAndCheck(t, contents)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the code block split across several", len(chunks))
+	}
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if !strings.HasPrefix(chunk, "````md\n") || !strings.HasSuffix(chunk, "\n````") {
+			t.Errorf("chunk %d is not wrapped in the four backtick code block", i+1)
+		}
+	}
+}
+
+func TestChunkContentsWrapsLongLines(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		// Whether each piece but the last should end at a space.
+		wrapsAtSpaces bool
+	}{
+		{"with spaces", strings.TrimSpace(strings.Repeat("word ", 1500)), true},
+		{"without spaces", strings.Repeat("a", 5000), false},
+		{"multibyte characters", strings.Repeat("é", 3000), false},
+		{"multibyte characters with spaces", strings.TrimSpace(strings.Repeat("日本語 ", 1000)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkAndCheck(t, tt.line)
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want the line wrapped across several", len(chunks))
+			}
+			// Pieces of a wrapped line are posted without line breaks between them.
+			if joined := strings.Join(chunks, ""); joined != tt.line {
+				t.Errorf("joined chunks differ from the line: got %d bytes, want %d", len(joined), len(tt.line))
+			}
+			if tt.wrapsAtSpaces {
+				for i, chunk := range chunks[:len(chunks)-1] {
+					if !strings.HasSuffix(chunk, " ") {
+						t.Errorf("chunk %d ends with %q, want it wrapped at a space", i+1, chunk[len(chunk)-5:])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestChunkContentsDoesNotPostEmptyCodeBlocks(t *testing.T) {
+	contents := "```go\n" + strings.Repeat("z", 5000) + "\n```"
+	chunks := chunkAndCheck(t, contents)
+	for i, chunk := range chunks {
+		if !strings.Contains(chunk, "z") {
+			t.Errorf("chunk %d is an empty code block: %q", i+1, chunk)
+		}
+		if !strings.HasPrefix(chunk, "```go\n") {
+			t.Errorf("chunk %d does not reopen the code block", i+1)
+		}
+	}
+}
+
+func TestChunkContentsHandlesOversizedFenceLines(t *testing.T) {
+	contents := "```" + strings.Repeat("x", 3000) + "\n" + codeLines(100, "line %d") + "\n```\nafter"
+	chunks := chunkAndCheck(t, contents)
+	// Repeating the whole opening line in every chunk would take hundreds of chunks.
+	if len(chunks) > 5 {
+		t.Errorf("got %d chunks, want the opening line wrapped once", len(chunks))
+	}
+	if last := chunks[len(chunks)-1]; !strings.HasSuffix(last, "after") {
+		t.Errorf("last chunk ends with %q, want the text after the code block", last)
+	}
+}
+
+func TestChunkContentsKeepsChunksWithinLimit(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&sb, "## Section %d\n\n", i)
+		fmt.Fprintf(&sb, "%s\n\n", strings.Repeat(fmt.Sprintf("Paragraph %d has some text. ", i), 20))
+		fmt.Fprintf(&sb, "```go\n%s\n```\n\n", codeLines(i%7*10, "x := %d"))
+		if i%10 == 0 {
+			fmt.Fprintf(&sb, "%s\n\n", strings.Repeat("ü", 2500))
+		}
+	}
+	chunks := chunkAndCheck(t, sb.String())
+	if len(chunks) == 0 {
+		t.Fatal("got no chunks")
+	}
+}
+
+func TestChunkContentsAnchorsBoundariesToContent(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "Paragraph %d of the file, with enough text to take up some room in a chunk.\n\n", i)
+	}
+	contents := sb.String()
+	paragraph := "Paragraph 100 of the file, with enough text to take up some room in a chunk.\n\n"
+
+	tests := []struct {
+		name   string
+		edited string
+	}{
+		{"edited paragraph", strings.Replace(contents, "Paragraph 100 of", "Paragraph 100, which was edited, of", 1)},
+		{"inserted paragraph", strings.Replace(contents, paragraph, paragraph+"A new paragraph.\n\n", 1)},
+		{"removed paragraph", strings.Replace(contents, paragraph, "", 1)},
+		{"edited first paragraph", "An introduction. " + contents},
+	}
+	before := chunkAndCheck(t, contents)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unchanged := make(map[string]bool, len(before))
+			for _, chunk := range before {
+				unchanged[chunk] = true
+			}
+			after := chunkAndCheck(t, tt.edited)
+			changed := 0
+			for _, chunk := range after {
+				if !unchanged[chunk] {
+					changed++
+				}
+			}
+			if changed > 2 {
+				t.Errorf("changed %d of %d chunks, want at most 2", changed, len(after))
+			}
+		})
+	}
+}
+
+func TestIsClosingFence(t *testing.T) {
+	tests := []struct {
+		text   string
+		marker string
+		want   bool
+	}{
+		{"```", "```", true},
+		{"````", "```", true},
+		{"   ```", "```", true},
+		{"    ```", "```", false},
+		{"``", "```", false},
+		{"```go", "```", false},
+		{"~~~", "```", false},
+		{"```", "````", false},
+		{"~~~~", "~~~", true},
+	}
+	for _, tt := range tests {
+		if got := isClosingFence(tt.text, tt.marker); got != tt.want {
+			t.Errorf("isClosingFence(%q, %q) = %v, want %v", tt.text, tt.marker, got, tt.want)
+		}
+	}
+}
@@ -1,259 +1,173 @@
 package commands
 
 import (
+	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/michaeldoylecs/discord-sync-bot/config"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/samber/lo"
 )
 
-type CommandHandler func(*discordgo.Session, *config.AppCtx)
+// How to handle a command or subcommand, and the components and modals it sends.
+type commandRoutes struct {
+	handler      InteractionHandler
+	autocomplete InteractionHandler
+	// Keyed by custom ID prefix.
+	components map[string]InteractionHandler
+	modals     map[string]InteractionHandler
+}
 
 type CommandConfig struct {
-	info    *discordgo.ApplicationCommand
-	handler CommandHandler
+	info   *discordgo.ApplicationCommand
+	routes commandRoutes
+	// Added to info's options when the command is registered.
+	subcommands []SubcommandConfig
+}
+
+// A subcommand, or a subcommand group when it has subcommands of its own.
+type SubcommandConfig struct {
+	info        *discordgo.ApplicationCommandOption
+	routes      commandRoutes
+	subcommands []SubcommandConfig
 }
 
+// The bot's commands all act on a guild's channels, so they are hidden in DMs.
+var allowInDMs = false
+
 // Keep sorted alphanumerically for readability.
 var commandConfigs = []CommandConfig{
-	commandConfigAddSync,
+	commandConfigShowSyncSource,
 	commandConfigSync,
+	commandConfigSyncPermissions,
+	commandConfigWrite,
 }
 
-func RegisterAllCommands(session *discordgo.Session, appCtx *config.AppCtx) {
-	// Handle globally registered commands
-	log.Info().Msg("Unregistering globally registered commands...")
-	unregisterGlobalCommands(session)
-	log.Info().Msg("Finished unregistering globally registered commands.")
-
-	// Handle guild registered commands
-	guildsCommandsMap := getCommandsForAllGuilds(session)
-	botCommandsMap := getAllBotCommands(commandConfigs)
-
-	// Add/Update guild commands
-	changedGuildsCommandsMap := filterCommandsToAdd(guildsCommandsMap, botCommandsMap)
-	log.Info().Msg("Started Registering all guild commands.")
-	logRegisterAllCommandsTime := logExecutionTime(log.Logger, "Finished registering all guild commands.")
-	for guildId, guildCommands := range changedGuildsCommandsMap {
-		log.Info().Str("guild_id", guildId).Msg("Started registering individual guild commands.")
-		logRegisterGuildCommands := logExecutionTime(log.Logger.With().Str("guild_id", guildId).Logger(),
-			"Finished registering individual guild commands.",
-		)
-		for _, cmd := range guildCommands {
-			log.Info().Str("guild_id", guildId).Interface("application_command", cmd).
-				Msg("Attemping to register command.")
-			regCmd, err := session.ApplicationCommandCreate(session.State.User.ID, guildId, cmd)
-			if err != nil {
-				log.Fatal().Err(err).Str("guild_id", guildId).Interface("application_command", cmd).
-					Msg("Failed to create command")
-			} else {
-				log.Info().Str("guild_id", guildId).Interface("application_command", regCmd).
-					Msg("Successfully registered command.")
-			}
-		}
-		logRegisterGuildCommands()
-	}
-	logRegisterAllCommandsTime()
+// How the bot's commands are registered with discord.
+type RegistrationMode string
 
-	// Remove guild commands
-	removeGuildsCommandsMap := filterCommandsToRemove(guildsCommandsMap, botCommandsMap)
-	log.Info().Msg("Started removing commands for all guilds.")
-	logRemoveAllCommandsTime := logExecutionTime(log.Logger, "Finished removing commands for all guilds.")
-	for guildId, guildCommands := range removeGuildsCommandsMap {
-		log.Info().Str("guild_id", guildId).Msg("Started removing commands for specific guild.")
-		logRemoveGuildCommandsTime := logExecutionTime(log.Logger.With().Str("guild_id", guildId).Logger(),
-			"Finished removing commands for specific guild.",
-		)
-		for _, cmd := range guildCommands {
-			err := session.ApplicationCommandDelete(session.State.User.ID, guildId, cmd.ID)
-			if err != nil {
-				log.Fatal().Err(err).Interface("command", cmd).Msg("Failed to remove command.")
-			} else {
-				log.Info().Str("guild_id", guildId).Interface("command", cmd).Msg("Successfully removed command.")
-			}
-		}
-		logRemoveGuildCommandsTime()
-	}
-	logRemoveAllCommandsTime()
+const (
+	// Registers the commands once for every guild. Discord can take a while to show changes.
+	RegistrationModeGlobal RegistrationMode = "global"
+	// Registers the commands in each guild separately. Changes show immediately.
+	RegistrationModeGuild RegistrationMode = "guild"
+	// Registers the commands only in the development guild, for testing changes.
+	RegistrationModeDevGuild RegistrationMode = "dev-guild"
+)
 
-	// Initialize command handlers
-	initializeCommandHandlers(session, appCtx)
+type RegistrationConfig struct {
+	Mode RegistrationMode
+	// Guild to register commands in when using RegistrationModeDevGuild.
+	DevGuildId string
 }
 
-func initializeCommandHandlers(session *discordgo.Session, appCtx *config.AppCtx) {
-	for _, config := range commandConfigs {
-		config.handler(session, appCtx)
-		log.Info().Str("command_name", config.info.Name).Msg("Command handler initialized.")
+func ParseRegistrationMode(mode string) (RegistrationMode, error) {
+	switch RegistrationMode(mode) {
+	case RegistrationModeGlobal, RegistrationModeGuild, RegistrationModeDevGuild:
+		return RegistrationMode(mode), nil
 	}
+	return "", fmt.Errorf("unknown command registration mode '%s'", mode)
 }
 
-func unregisterGlobalCommands(session *discordgo.Session) {
-	// Get globally registered commands
-	globallyRegisteredCommands, err := session.ApplicationCommands(session.State.User.ID, "")
-	if err != nil {
-		log.Fatal().Err(err)
-	}
+func RegisterAllCommands(session *discordgo.Session, appCtx *config.AppCtx, registration RegistrationConfig) {
+	logger := log.With().Str("registration_mode", string(registration.Mode)).Logger()
+	logger.Info().Msg("Started registering commands.")
+	logRegisterAllCommandsTime := logExecutionTime(logger, "Finished registering commands.")
 
-	// Remove globally registered commands
-	for _, command := range globallyRegisteredCommands {
-		err := session.ApplicationCommandDelete(session.State.User.ID, "", command.ID)
-		if err != nil {
-			log.Fatal().Err(err).Str("command_name", command.Name).Msg("Failed to remove command.")
-		}
-		log.Info().Str("command_name", command.Name).Msg("Successfully removed command.")
+	guildIds := make([]string, 0, len(session.State.Guilds))
+	for _, guild := range session.State.Guilds {
+		guildIds = append(guildIds, guild.ID)
 	}
-}
 
-func getAllBotCommands(commandConfigs []CommandConfig) map[string]*discordgo.ApplicationCommand {
-	commandMap := make(map[string]*discordgo.ApplicationCommand)
-	for _, config := range commandConfigs {
-		commandMap[config.info.Name] = config.info
-	}
-	return commandMap
-}
+	// Localizing writes to the shared command definitions, so it is done once here rather than
+	// each time commands are registered, which may happen concurrently from guild events.
+	botCommands := getAllBotCommands(commandConfigs)
 
-func getCommandsForAllGuilds(session *discordgo.Session) map[string]map[string]*discordgo.ApplicationCommand {
-	guildIds := lo.Map(session.State.Guilds, func(guild *discordgo.Guild, _ int) string {
-		return guild.ID
-	})
-
-	guildCommandsMap := make(map[string]map[string]*discordgo.ApplicationCommand)
-
-	for _, guildId := range guildIds {
-		commands, err := session.ApplicationCommands(session.State.User.ID, guildId)
-		if err != nil {
-			log.Fatal().Err(err)
-		}
-
-		commandMap := make(map[string]*discordgo.ApplicationCommand)
-		for _, cmd := range commands {
-			commandMap[cmd.Name] = cmd
+	// Commands left over from another mode would show up twice, so clear them.
+	switch registration.Mode {
+	case RegistrationModeGlobal:
+		overwriteCommands(session, "", botCommands)
+		for _, guildId := range guildIds {
+			overwriteCommands(session, guildId, []*discordgo.ApplicationCommand{})
 		}
-		guildCommandsMap[guildId] = commandMap
-	}
-
-	return guildCommandsMap
-}
-
-func filterCommandsToAdd(guildsCommands map[string]map[string]*discordgo.ApplicationCommand, botCommands map[string]*discordgo.ApplicationCommand) map[string]map[string]*discordgo.ApplicationCommand {
-	newGuildsCommands := make(map[string]map[string]*discordgo.ApplicationCommand)
-	for guildId, guildCommands := range guildsCommands {
-		// Get commands guild does not have registered
-		unregisteredCommands := make(map[string]*discordgo.ApplicationCommand)
-		for botCmdName, botCmd := range botCommands {
-			if _, found := guildCommands[botCmdName]; !found {
-				log.Info().Str("guild_id", guildId).Str("command_name", botCmdName).
-					Msg("Command not found in guild, adding.")
-				unregisteredCommands[botCmdName] = botCmd
+	case RegistrationModeGuild:
+		overwriteCommands(session, "", []*discordgo.ApplicationCommand{})
+		for _, guildId := range guildIds {
+			if overwriteCommands(session, guildId, botCommands) {
+				markGuildRegistered(guildId)
 			}
 		}
-
-		// Get commands that have changed
-		changedCommands := make(map[string]*discordgo.ApplicationCommand)
-		for _, cmd := range guildCommands {
-			// Do not try to update guild command if not is bot's command list.
-			// It will be handled in command removal.
-			if botCommands[cmd.Name] == nil {
-				continue
+	case RegistrationModeDevGuild:
+		overwriteCommands(session, "", []*discordgo.ApplicationCommand{})
+		for _, guildId := range guildIds {
+			if guildId != registration.DevGuildId {
+				overwriteCommands(session, guildId, []*discordgo.ApplicationCommand{})
 			}
-			if !botCommandAndRegisteredCommandAreEqual(botCommands[cmd.Name], cmd) {
-				log.Debug().
-					Str("guild_id", guildId).
-					Interface("bot_command", botCommands[cmd.Name]).
-					Interface("registered_command", cmd).
-					Msg("Bot command differs from registered guild command.")
-				changedCommands[cmd.Name] = botCommands[cmd.Name]
-			} else {
-				log.Info().Str("guild_id", guildId).Str("command_name", cmd.Name).
-					Msg("Bot command matches registered guild command, ignoring.")
-			}
-		}
-
-		allCommandsToRegister := make(map[string]*discordgo.ApplicationCommand)
-		for cmdName, cmd := range unregisteredCommands {
-			allCommandsToRegister[cmdName] = cmd
 		}
-		for cmdName, cmd := range changedCommands {
-			allCommandsToRegister[cmdName] = cmd
-		}
-
-		newGuildsCommands[guildId] = allCommandsToRegister
+		overwriteCommands(session, registration.DevGuildId, botCommands)
 	}
-	return newGuildsCommands
-}
+	logRegisterAllCommandsTime()
 
-func filterCommandsToRemove(guildsCommands map[string]map[string]*discordgo.ApplicationCommand, botCommands map[string]*discordgo.ApplicationCommand) map[string]map[string]*discordgo.ApplicationCommand {
-	guildsCommandsToRemove := make(map[string]map[string]*discordgo.ApplicationCommand)
-	for guildId, guildCommands := range guildsCommands {
-		commandsToRemove := make(map[string]*discordgo.ApplicationCommand)
-		for cmdName, cmd := range guildCommands {
-			if _, found := botCommands[cmdName]; !found {
-				commandsToRemove[cmdName] = cmd
-			}
-		}
-		guildsCommandsToRemove[guildId] = commandsToRemove
-	}
-	return guildsCommandsToRemove
+	// Initialize command handlers
+	initializeCommandHandlers(session, appCtx)
+	initializeGuildEventHandlers(session, appCtx, registration, botCommands)
 }
 
-// ORDER OF ARGUMENTS MAY MATTER!
-// Keep commented out logging for future addition of debug logging.
-func botCommandAndRegisteredCommandAreEqual(botCmd *discordgo.ApplicationCommand, regCmd *discordgo.ApplicationCommand) bool {
-	// log.Printf("'%s' '%s'\n", botCmd.Name, regCmd.Name)
-	if !cmp.Equal(botCmd.Name, regCmd.Name) {
-		return false
-	}
-
-	// cmd1nl, _ := json.Marshal(botCmd.NameLocalizations)
-	// cmd2nl, _ := json.Marshal(regCmd.NameLocalizations)
-	// log.Printf("'%s' '%s'\n", string(cmd1nl), string(cmd2nl))
-	if !cmp.Equal(botCmd.NameLocalizations, regCmd.NameLocalizations) {
-		return false
-	}
-
-	// log.Printf("'%s' '%s'\n", botCmd.Description, regCmd.Description)
-	if !cmp.Equal(botCmd.Description, regCmd.Description) {
-		return false
-	}
-
-	// cmd1dl, _ := json.Marshal(botCmd.DescriptionLocalizations)
-	// cmd2dl, _ := json.Marshal(regCmd.DescriptionLocalizations)
-	// log.Printf("'%s' '%s'\n", string(cmd1dl), string(cmd2dl))
-	if !cmp.Equal(botCmd.DescriptionLocalizations, regCmd.DescriptionLocalizations) {
+// Replaces the bot's commands in a guild, or its global commands if guildId is empty.
+// Failures are logged rather than stopping the bot, so one broken guild does not affect the others.
+func overwriteCommands(session *discordgo.Session, guildId string, commands []*discordgo.ApplicationCommand) bool {
+	logger := log.With().Str("guild_id", guildId).Int("command_count", len(commands)).Logger()
+	_, err := session.ApplicationCommandBulkOverwrite(session.State.User.ID, guildId, commands)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to overwrite commands.")
 		return false
 	}
+	logger.Info().Msg("Successfully overwrote commands.")
+	return true
+}
 
-	dmpdefault := int64(0)
-	if botCmd.DefaultMemberPermissions == nil {
-		botCmd.DefaultMemberPermissions = &dmpdefault
-	}
-	if regCmd.DefaultMemberPermissions == nil {
-		regCmd.DefaultMemberPermissions = &dmpdefault
-	}
-	// log.Printf("'%b' '%b'\n", *botCmd.DefaultMemberPermissions, *regCmd.DefaultMemberPermissions)
-	if !cmp.Equal(botCmd.DefaultMemberPermissions, regCmd.DefaultMemberPermissions) {
-		return false
+func initializeCommandHandlers(session *discordgo.Session, appCtx *config.AppCtx) {
+	router := newRouter(appCtx, recoverPanics, logInteraction)
+	for _, config := range commandConfigs {
+		router.addCommand(config)
+		log.Info().Str("command_name", config.info.Name).Msg("Command handler initialized.")
 	}
+	session.AddHandler(router.handle)
+}
 
-	// log.Printf("'%v' '%v'\n", botCmd.Options, regCmd.Options)
-	if !cmp.Equal(botCmd.Options, regCmd.Options) {
-		return false
+// Builds the localized definitions of the bot's commands. The definitions share their options with
+// commandConfigs and are localized in place, so build them once and reuse them.
+func getAllBotCommands(commandConfigs []CommandConfig) []*discordgo.ApplicationCommand {
+	commands := make([]*discordgo.ApplicationCommand, 0, len(commandConfigs))
+	for _, config := range commandConfigs {
+		info := config.info
+		if len(config.subcommands) > 0 {
+			withSubcommands := *config.info
+			withSubcommands.Options = subcommandOptions(config.subcommands)
+			info = &withSubcommands
+		}
+		localizeCommand(info)
+		commands = append(commands, info)
 	}
+	return commands
+}
 
-	nsfwdefault := false
-	if botCmd.NSFW == nil {
-		botCmd.NSFW = &nsfwdefault
-	}
-	if regCmd.NSFW == nil {
-		regCmd.NSFW = &nsfwdefault
+func subcommandOptions(subcommands []SubcommandConfig) []*discordgo.ApplicationCommandOption {
+	options := make([]*discordgo.ApplicationCommandOption, 0, len(subcommands))
+	for _, subcommand := range subcommands {
+		option := subcommand.info
+		if len(subcommand.subcommands) > 0 {
+			group := *subcommand.info
+			group.Options = subcommandOptions(subcommand.subcommands)
+			option = &group
+		}
+		options = append(options, option)
 	}
-	// log.Printf("'%t' '%t'\n", *botCmd.NSFW, *regCmd.NSFW)
-	return (*botCmd.NSFW == *regCmd.NSFW)
+	return options
 }
 
 func NewTraceLogger() zerolog.Logger {
@@ -272,8 +186,18 @@ func newInteractionLogger(interaction *discordgo.Interaction) zerolog.Logger {
 
 	isDM := interaction.Member == nil
 
-	return NewTraceLogger().With().
-		Str("interaction_command_name", interaction.ApplicationCommandData().Name).
+	loggerCtx := NewTraceLogger().With()
+	switch interaction.Type {
+	case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+		path, _ := commandPath(interaction.ApplicationCommandData())
+		loggerCtx = loggerCtx.Str("interaction_command_name", path)
+	case discordgo.InteractionMessageComponent:
+		loggerCtx = loggerCtx.Str("interaction_custom_id", interaction.MessageComponentData().CustomID)
+	case discordgo.InteractionModalSubmit:
+		loggerCtx = loggerCtx.Str("interaction_custom_id", interaction.ModalSubmitData().CustomID)
+	}
+
+	return loggerCtx.
 		Str("interaction_guild_id", interaction.GuildID).
 		Str("interaction_channel_id", interaction.ChannelID).
 		Str("interaction_user_id", userId).
@@ -310,5 +234,66 @@ func sendEphemeralResponse(session *discordgo.Session, interaction *discordgo.In
 }
 
 func sendErrorResponse(session *discordgo.Session, interaction *discordgo.Interaction) {
-	sendEphemeralResponse(session, interaction, "Something went wrong. Please try again or contact bot owner.")
+	sendEphemeralResponse(session, interaction, localize(interaction, "Something went wrong. Please try again or contact bot owner."))
+}
+
+// Replaces the message a component was attached to, removing its components.
+func sendUpdateMessageResponse(session *discordgo.Session, interaction *discordgo.Interaction, msg string) {
+	session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// Acknowledges an interaction so it can be responded to after Discord's 3 second deadline.
+// Follow with editResponse.
+func sendDeferredEphemeralResponse(session *discordgo.Session, interaction *discordgo.Interaction) error {
+	return session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func editResponse(session *discordgo.Session, interaction *discordgo.Interaction, msg string) {
+	session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+		Content: &msg,
+	})
+}
+
+func editErrorResponse(session *discordgo.Session, interaction *discordgo.Interaction) {
+	editResponse(session, interaction, localize(interaction, "Something went wrong. Please try again or contact bot owner."))
+}
+
+// Maximum length of a discord message's content.
+const maxMessageLength = 2000
+
+// Shortens msg to fit within a single discord message.
+func truncateMessage(msg string) string {
+	return truncateText(msg, maxMessageLength)
+}
+
+// Shortens text to at most maxLength bytes, without cutting a character in half.
+func truncateText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	suffix := "\n..."
+	cut := maxLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + suffix
+}
+
+// Formats a timestamp as a relative discord timestamp, or "Never" if it is not set.
+func formatDiscordTimestamp(interaction *discordgo.Interaction, timestamp pgtype.Timestamptz) string {
+	if !timestamp.Valid {
+		return localize(interaction, "Never")
+	}
+	return fmt.Sprintf("<t:%d:R>", timestamp.Time.Unix())
 }
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Maximum number of lines on either side of a diff. Comparing takes memory proportional to the product
+// of the two line counts, so longer text is only reported as changed.
+const maxDiffLines = 1000
+
+// Computes a line based diff between old and new using the longest common subsequence of lines.
+// Removed lines are prefixed with "- " and added lines with "+ ". Unchanged lines are omitted.
+// Text of more than maxDiffLines lines is not compared, and a single line saying it changed is returned instead.
+func lineDiff(old string, new string) []string {
+	oldLines := strings.Split(old, "\n")
+	newLines := strings.Split(new, "\n")
+	if len(oldLines) > maxDiffLines || len(newLines) > maxDiffLines {
+		return []string{fmt.Sprintf("~ changed from %d to %d lines, too long to compare", len(oldLines), len(newLines))}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]string, 0)
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+oldLines[i])
+			i++
+		default:
+			diff = append(diff, "+ "+newLines[j])
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		diff = append(diff, "- "+oldLines[i])
+	}
+	for ; j < len(newLines); j++ {
+		diff = append(diff, "+ "+newLines[j])
+	}
+	return diff
+}
@@ -0,0 +1,459 @@
+package commands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+var subcommandConfigSyncEdit = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "edit",
+		Description: "Change an existing sync, keeping its messages",
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "file-uri",
+				Description:  "New file URI",
+				Required:     false,
+				Autocomplete: true,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "github-repo-url",
+				Description:  "New GitHub repo URL",
+				Required:     false,
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "unlink-github-repo",
+				Description: "Stop syncing when the linked GitHub repo is pushed to",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "suppress-embeds",
+				Description: "Hide link previews on the synced messages",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "pin-messages",
+				Description: "Pin the synced messages",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "refresh-button",
+				Description: "Add a button to the last synced message that lets anyone refresh it",
+				Required:    false,
+			},
+			headingOption(),
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "remove-heading",
+				Description: "Remove the heading shown above the file's first message",
+				Required:    false,
+			},
+			attachmentModeOption(),
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleEditSyncCommand),
+		autocomplete: requireSyncPermission(autocompleteEditSync),
+	},
+}
+
+func handleEditSyncCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	channelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		sendEphemeralResponse(session, interaction, localize(interaction, "Either channel or channel-id must be given."))
+		return
+	}
+
+	var edit syncEdit
+	if opt, ok := optionMap["file-uri"]; ok {
+		fileUri := opt.StringValue()
+		edit.fileUri = &fileUri
+	}
+	if opt, ok := optionMap["github-repo-url"]; ok {
+		githubRepoUrl := opt.StringValue()
+		edit.githubRepoUrl = &githubRepoUrl
+	}
+	if opt, ok := optionMap["unlink-github-repo"]; ok {
+		edit.unlinkGithubRepo = opt.BoolValue()
+	}
+	if opt, ok := optionMap["suppress-embeds"]; ok {
+		suppressEmbeds := opt.BoolValue()
+		edit.suppressEmbeds = &suppressEmbeds
+	}
+	if opt, ok := optionMap["pin-messages"]; ok {
+		pinMessages := opt.BoolValue()
+		edit.pinMessages = &pinMessages
+	}
+	if opt, ok := optionMap["refresh-button"]; ok {
+		refreshButton := opt.BoolValue()
+		edit.refreshButton = &refreshButton
+	}
+
+	if opt, ok := optionMap["heading"]; ok {
+		heading := opt.StringValue()
+		edit.heading = &heading
+	}
+	if opt, ok := optionMap["remove-heading"]; ok && opt.BoolValue() {
+		if edit.heading != nil {
+			sendEphemeralResponse(session, interaction, localize(interaction, "heading and remove-heading can't be used together."))
+			return
+		}
+		heading := ""
+		edit.heading = &heading
+	}
+	if opt, ok := optionMap["attachment-mode"]; ok {
+		attachmentMode := opt.StringValue()
+		edit.attachmentMode = &attachmentMode
+	}
+
+	if edit.githubRepoUrl != nil && edit.unlinkGithubRepo {
+		sendEphemeralResponse(session, interaction, localize(interaction, "github-repo-url and unlink-github-repo can't be used together."))
+		return
+	}
+	if edit.fileUri == nil && edit.githubRepoUrl == nil && !edit.unlinkGithubRepo && edit.suppressEmbeds == nil && edit.pinMessages == nil && edit.refreshButton == nil && edit.heading == nil && edit.attachmentMode == nil {
+		sendEphemeralResponse(session, interaction, localize(interaction, "Nothing to change. Give at least one of file-uri, github-repo-url, unlink-github-repo, suppress-embeds, pin-messages, refresh-button, heading, remove-heading, or attachment-mode."))
+		return
+	}
+
+	fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, channelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if msg != "" {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+
+	// A channel can only sync each file once.
+	if edit.fileUri != nil && *edit.fileUri != fileToSync.FileToSyncUri {
+		channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(context.Background(), db.GetGuildChannelSyncsParams{
+			GuildID:   interaction.GuildID,
+			ChannelID: channelId,
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			sendErrorResponse(session, interaction)
+			return
+		}
+		for _, channelSync := range channelSyncs {
+			if channelSync.FileToSyncUri == *edit.fileUri {
+				sendEphemeralResponse(session, interaction, localize(interaction, "<#%s> already syncs %s.", channelId, *edit.fileUri))
+				return
+			}
+		}
+	}
+
+	// Updating every chunk message can take longer than the interaction deadline.
+	err = sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	ctx := logger.WithContext(context.Background())
+	err = applySyncEdit(ctx, *appCtx, fileToSync.ID, edit)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+
+	lines := []string{localize(interaction, "Updated sync for <#%s>.", channelId)}
+	if edit.fileUri != nil {
+		lines = append(lines, localize(interaction, "Source changed to %s. Run /sync run to update the messages in place.", *edit.fileUri))
+	}
+	if edit.githubRepoUrl != nil {
+		lines = append(lines, localize(interaction, "Linked to GitHub repo %s.", *edit.githubRepoUrl))
+	}
+	if edit.unlinkGithubRepo {
+		lines = append(lines, localize(interaction, "Unlinked GitHub repo."))
+	}
+	if edit.suppressEmbeds != nil && *edit.suppressEmbeds != fileToSync.SuppressEmbeds {
+		fileToSync.SuppressEmbeds = *edit.suppressEmbeds
+		line := localize(interaction, "Link previews are now shown.")
+		if fileToSync.SuppressEmbeds {
+			line = localize(interaction, "Link previews are now hidden.")
+		}
+		failed, err := updateChunkMessages(ctx, *appCtx, fileToSync, func(channelId string, messageId string) error {
+			return setMessageFlags(session, channelId, messageId, chunkMessageFlags(fileToSync))
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			line += " " + localize(interaction, "Failed to update the existing messages.")
+		} else if failed > 0 {
+			line += " " + localize(interaction, "Failed to update %d messages.", failed)
+		}
+		lines = append(lines, line)
+	}
+	if edit.pinMessages != nil && *edit.pinMessages != fileToSync.PinMessages {
+		fileToSync.PinMessages = *edit.pinMessages
+		line := localize(interaction, "Messages are no longer pinned.")
+		if fileToSync.PinMessages {
+			line = localize(interaction, "Messages are now pinned.")
+		}
+		failed, err := updateChunkMessages(ctx, *appCtx, fileToSync, func(channelId string, messageId string) error {
+			if fileToSync.PinMessages {
+				return session.ChannelMessagePin(channelId, messageId)
+			}
+			return session.ChannelMessageUnpin(channelId, messageId)
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			line += " " + localize(interaction, "Failed to update the existing messages.")
+		} else if failed > 0 {
+			line += " " + localize(interaction, "Failed to update %d messages.", failed)
+		}
+		lines = append(lines, line)
+	}
+	if edit.refreshButton != nil && *edit.refreshButton != fileToSync.RefreshButton {
+		fileToSync.RefreshButton = *edit.refreshButton
+		line := localize(interaction, "Removed the refresh button.")
+		if fileToSync.RefreshButton {
+			line = localize(interaction, "Added a refresh button to the last message.")
+		}
+		err := updateRefreshButton(ctx, *appCtx, fileToSync)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			line += " " + localize(interaction, "Failed to update the existing messages.")
+		}
+		lines = append(lines, line)
+	}
+	if edit.heading != nil && *edit.heading != fileToSync.Heading {
+		fileToSync.Heading = *edit.heading
+		line := localize(interaction, "Removed the heading.")
+		if fileToSync.Heading != "" {
+			line = localize(interaction, "Changed the heading to %s.", fileToSync.Heading)
+		}
+		err := updateHeading(ctx, *appCtx, fileToSync)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			line += " " + localize(interaction, "Failed to update the existing messages.")
+		}
+		lines = append(lines, line)
+	}
+	if edit.attachmentMode != nil && *edit.attachmentMode != fileToSync.AttachmentMode {
+		fileToSync.AttachmentMode = *edit.attachmentMode
+		var line string
+		switch fileToSync.AttachmentMode {
+		case attachmentModeMessages:
+			line = localize(interaction, "The file is now always posted as messages.")
+		case attachmentModeAttachment:
+			line = localize(interaction, "The file is now always posted as an attachment.")
+		default:
+			line = localize(interaction, "The file is now posted as an attachment once it is over %d KB.", attachmentModeThreshold/1024)
+		}
+		err := updateAttachmentMode(ctx, *appCtx, fileToSync)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			line += " " + localize(interaction, "Failed to update the existing messages.")
+		}
+		lines = append(lines, line)
+	}
+	editResponse(session, interaction, truncateMessage(strings.Join(lines, "\n")))
+}
+
+// Changes to make to a sync. Nil fields are left as they are.
+type syncEdit struct {
+	fileUri          *string
+	githubRepoUrl    *string
+	unlinkGithubRepo bool
+	suppressEmbeds   *bool
+	pinMessages      *bool
+	refreshButton    *bool
+	heading          *string
+	attachmentMode   *string
+}
+
+// Updates the sync's records in a single transaction. Its chunk messages are kept,
+// so the next sync edits them in place.
+func applySyncEdit(ctx context.Context, appCtx config.AppCtx, fileToSyncId int64, edit syncEdit) error {
+	tx, err := appCtx.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := appCtx.DB.WithTx(tx)
+	if edit.fileUri != nil {
+		err := queries.SetFileSyncUri(ctx, db.SetFileSyncUriParams{
+			FileUri:      *edit.fileUri,
+			FileToSyncID: fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if edit.githubRepoUrl != nil {
+		_, err := queries.AddGithubRepoFile(ctx, db.AddGithubRepoFileParams{
+			GithubRepoUrl: *edit.githubRepoUrl,
+			FileToSyncFk:  fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if edit.unlinkGithubRepo {
+		if err := queries.RemoveGithubRepoFile(ctx, fileToSyncId); err != nil {
+			return err
+		}
+	}
+	if edit.suppressEmbeds != nil {
+		err := queries.SetFileSyncSuppressEmbeds(ctx, db.SetFileSyncSuppressEmbedsParams{
+			SuppressEmbeds: *edit.suppressEmbeds,
+			FileToSyncID:   fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if edit.pinMessages != nil {
+		err := queries.SetFileSyncPinMessages(ctx, db.SetFileSyncPinMessagesParams{
+			PinMessages:  *edit.pinMessages,
+			FileToSyncID: fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if edit.refreshButton != nil {
+		err := queries.SetFileSyncRefreshButton(ctx, db.SetFileSyncRefreshButtonParams{
+			RefreshButton: *edit.refreshButton,
+			FileToSyncID:  fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if edit.heading != nil {
+		err := queries.SetFileSyncHeading(ctx, db.SetFileSyncHeadingParams{
+			Heading:      *edit.heading,
+			FileToSyncID: fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if edit.attachmentMode != nil {
+		err := queries.SetFileSyncAttachmentMode(ctx, db.SetFileSyncAttachmentModeParams{
+			AttachmentMode: *edit.attachmentMode,
+			FileToSyncID:   fileToSyncId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Applies update to each of the sync's existing chunk messages.
+// Returns the number of messages that could not be updated.
+func updateChunkMessages(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync, update func(channelId string, messageId string) error) (int, error) {
+	logger := zerolog.Ctx(ctx)
+
+	chunkRows, err := appCtx.DB.GetFileContentChunks(ctx, fileToSync.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	messageChannelId := fileToSync.DiscordChannelSnowflake
+	if fileToSync.DiscordThreadSnowflake != "" {
+		messageChannelId = fileToSync.DiscordThreadSnowflake
+	}
+
+	failed := 0
+	for _, chunkRow := range chunkRows {
+		err := update(messageChannelId, chunkRow.DiscordMessageID)
+		if err != nil {
+			logger.Warn().Err(err).Str("message_id", chunkRow.DiscordMessageID).Msg("Failed to update message chunk.")
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// Edits the sync's first chunk message to show its heading above the last synced contents.
+func updateHeading(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync) error {
+	chunkRows, err := appCtx.DB.GetFileContentChunks(ctx, fileToSync.ID)
+	if err != nil {
+		return err
+	}
+	contents, contentHashes, _ := fileMessageContents(fileToSync, fileToSync.FileContents)
+	if len(chunkRows) == 0 || len(contents) == 0 {
+		return nil
+	}
+
+	messageChannelId := fileToSync.DiscordChannelSnowflake
+	if fileToSync.DiscordThreadSnowflake != "" {
+		messageChannelId = fileToSync.DiscordThreadSnowflake
+	}
+
+	// Editing only the content keeps any attachment the message has.
+	_, err = appCtx.DiscordSession.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:      chunkRows[0].DiscordMessageID,
+		Channel: messageChannelId,
+		Content: &contents[0],
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keep the stored hash in step, so the next sync knows the message already shows the heading.
+	return appCtx.DB.SetFileContentChunkHash(ctx, db.SetFileContentChunkHashParams{
+		ContentHash:      contentHashes[0],
+		DiscordMessageID: chunkRows[0].DiscordMessageID,
+	})
+}
+
+// Posts the sync's last synced contents again in its new attachment mode, replacing its messages with
+// an attachment or the other way around. Messages already in the right form are left alone.
+func updateAttachmentMode(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync) error {
+	var result SyncResult
+	appended, err := postFileChunks(ctx, appCtx, fileToSync, fileToSync.FileContents, &result, func(string, ...interface{}) {})
+	if err != nil {
+		return err
+	}
+	if appended {
+		return repostFollowingFiles(ctx, appCtx, fileToSync, &result)
+	}
+	return nil
+}
+
+// Edits a message's flags. discordgo's MessageEdit omits empty flags, which would leave
+// previously suppressed embeds hidden, so the request is made directly.
+func setMessageFlags(session *discordgo.Session, channelId string, messageId string, flags discordgo.MessageFlags) error {
+	data := struct {
+		Flags discordgo.MessageFlags `json:"flags"`
+	}{flags}
+	_, err := session.RequestWithBucketID("PATCH", discordgo.EndpointChannelMessage(channelId, messageId), data, discordgo.EndpointChannelMessage(channelId, ""))
+	return err
+}
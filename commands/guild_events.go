@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Guilds whose commands are already registered. Discord sends a GuildCreate for every guild on connect
+// and whenever a guild recovers from an outage, so these must not be registered again.
+var (
+	registeredGuildIds   = make(map[string]bool)
+	registeredGuildIdsMu sync.Mutex
+)
+
+// Marks the guild as registered, returning false if it already was.
+func markGuildRegistered(guildId string) bool {
+	registeredGuildIdsMu.Lock()
+	defer registeredGuildIdsMu.Unlock()
+	if registeredGuildIds[guildId] {
+		return false
+	}
+	registeredGuildIds[guildId] = true
+	return true
+}
+
+func unmarkGuildRegistered(guildId string) {
+	registeredGuildIdsMu.Lock()
+	defer registeredGuildIdsMu.Unlock()
+	delete(registeredGuildIds, guildId)
+}
+
+// botCommands are the commands registered in guilds the bot joins, when registering per guild.
+func initializeGuildEventHandlers(session *discordgo.Session, appCtx *config.AppCtx, registration RegistrationConfig, botCommands []*discordgo.ApplicationCommand) {
+	session.AddHandler(func(session *discordgo.Session, event *discordgo.GuildCreate) {
+		// Only per guild registration needs commands registered in new guilds.
+		if registration.Mode != RegistrationModeGuild || !markGuildRegistered(event.ID) {
+			return
+		}
+
+		logger := NewTraceLogger().With().Str("guild_id", event.ID).Logger()
+		defer logExecutionTime(logger, "Finished registering commands for joined guild.")()
+		logger.Info().Msg("Joined guild, registering commands.")
+
+		if !overwriteCommands(session, event.ID, botCommands) {
+			// Allow a later GuildCreate for this guild to retry.
+			unmarkGuildRegistered(event.ID)
+		}
+	})
+	log.Info().Msg("Guild create handler initialized.")
+
+	session.AddHandler(func(session *discordgo.Session, event *discordgo.GuildDelete) {
+		// An unavailable guild is having an outage; the bot is still a member.
+		if event.Unavailable {
+			return
+		}
+		unmarkGuildRegistered(event.ID)
+
+		logger := NewTraceLogger().With().Str("guild_id", event.ID).Logger()
+		defer logExecutionTime(logger, "Finished cleaning up removed guild.")()
+		logger.Info().Msg("Removed from guild, removing its syncs.")
+
+		err := removeGuildSyncs(logger.WithContext(context.Background()), *appCtx, event.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to remove syncs for removed guild.")
+		}
+	})
+	log.Info().Msg("Guild delete handler initialized.")
+}
+
+// Removes every sync record and sync permission for a guild in a single transaction,
+// since the guild won't be cleaned up again if only some are removed.
+// The synced messages are left alone, since the bot can no longer reach them.
+func removeGuildSyncs(ctx context.Context, appCtx config.AppCtx, guildId string) error {
+	tx, err := appCtx.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := appCtx.DB.WithTx(tx)
+	fileSyncs, err := queries.GetGuildSyncs(ctx, guildId)
+	if err != nil {
+		return err
+	}
+
+	for _, fileSync := range fileSyncs {
+		if err := removeChannelSyncRecords(ctx, queries, fileSync.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := queries.RemoveGuildSyncPermissions(ctx, guildId); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
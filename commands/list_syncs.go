@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	listSyncsPagePrefix = "list-syncs:page:"
+	listSyncsPageSize   = 5
+)
+
+var subcommandConfigSyncList = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "list",
+		Description: "List every channel sync in this guild.",
+	},
+	routes: commandRoutes{
+		handler: requireSyncPermission(handleListSyncsCommand),
+		components: map[string]InteractionHandler{
+			listSyncsPagePrefix: requireSyncPermission(handleListSyncsPageButton),
+		},
+	},
+}
+
+func handleListSyncsCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	respondSyncListPage(session, interaction, appCtx, logger, 0, discordgo.InteractionResponseChannelMessageWithSource)
+}
+
+// Moves the list to the page in the button's custom ID.
+func handleListSyncsPageButton(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	page, err := strconv.Atoi(strings.TrimPrefix(interaction.MessageComponentData().CustomID, listSyncsPagePrefix))
+	if err != nil {
+		logger.Error().Err(err).Msg("Malformed page button custom ID.")
+		return
+	}
+	respondSyncListPage(session, interaction, appCtx, logger, page, discordgo.InteractionResponseUpdateMessage)
+}
+
+func respondSyncListPage(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger, page int, responseType discordgo.InteractionResponseType) {
+	logger.Info().Int("page", page).Msg("Listing syncs.")
+
+	syncs, err := appCtx.DB.GetGuildSyncSummaries(context.Background(), interaction.GuildID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		respondSyncList(session, interaction, logger, responseType, localize(interaction, "Something went wrong. Please try again or contact bot owner."), nil, nil)
+		return
+	}
+
+	if len(syncs) == 0 {
+		respondSyncList(session, interaction, logger, responseType, localize(interaction, "No channels are being synced in this guild."), nil, nil)
+		return
+	}
+
+	embed, components := buildSyncListPage(interaction, syncs, page)
+	respondSyncList(session, interaction, logger, responseType, "", []*discordgo.MessageEmbed{embed}, components)
+}
+
+// Shows the list, replacing the page and its buttons in place when responding to a page button.
+func respondSyncList(session *discordgo.Session, interaction *discordgo.Interaction, logger zerolog.Logger, responseType discordgo.InteractionResponseType, content string, embeds []*discordgo.MessageEmbed, components []discordgo.MessageComponent) {
+	if embeds == nil {
+		embeds = []*discordgo.MessageEmbed{}
+	}
+	if components == nil {
+		components = []discordgo.MessageComponent{}
+	}
+	err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Embeds:     embeds,
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+// Builds the embed and navigation buttons for a single page of syncs.
+// Out of range pages are clamped to the first or last page.
+func buildSyncListPage(interaction *discordgo.Interaction, syncs []db.GetGuildSyncSummariesRow, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	pageCount := (len(syncs) + listSyncsPageSize - 1) / listSyncsPageSize
+	if page >= pageCount {
+		page = pageCount - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * listSyncsPageSize
+	end := start + listSyncsPageSize
+	if end > len(syncs) {
+		end = len(syncs)
+	}
+
+	entries := make([]string, 0, end-start)
+	for _, sync := range syncs[start:end] {
+		githubRepo := localize(interaction, "None")
+		if sync.GithubRepoUrl.Valid {
+			githubRepo = sync.GithubRepoUrl.String
+		}
+		channel := fmt.Sprintf("<#%s>", sync.ChannelID)
+		if sync.Paused {
+			channel = localize(interaction, "<#%s> (paused)", sync.ChannelID)
+		}
+		entries = append(entries, localize(interaction,
+			"%s\nSource: %s\nGitHub repo: %s\nChunks: %d\nLast synced: %s",
+			channel, sync.Url, githubRepo, sync.ChunkCount, formatDiscordTimestamp(interaction, sync.LastSyncedAt),
+		))
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       localize(interaction, "Channel Syncs"),
+		Description: strings.Join(entries, "\n\n"),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: localize(interaction, "Page %d/%d (%d syncs)", page+1, pageCount, len(syncs)),
+		},
+	}
+
+	components := []discordgo.MessageComponent{}
+	if pageCount > 1 {
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    localize(interaction, "Previous"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: listSyncsPagePrefix + strconv.Itoa(page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    localize(interaction, "Next"),
+					Style:    discordgo.SecondaryButton,
+					CustomID: listSyncsPagePrefix + strconv.Itoa(page+1),
+					Disabled: page == pageCount-1,
+				},
+			},
+		})
+	}
+
+	return embed, components
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/locales"
+)
+
+// Formats a response in the language of the member who used the interaction.
+// format is looked up in the locale catalogs as written, so pass it as a literal.
+func localize(interaction *discordgo.Interaction, format string, args ...interface{}) string {
+	translated := locales.Translate(interaction.Locale, format)
+	if len(args) == 0 {
+		return translated
+	}
+	return fmt.Sprintf(translated, args...)
+}
+
+// Fills in the localized names and descriptions of a command and its options from the locale catalogs.
+// Only subcommands have their names localized. Other option names are left as they are,
+// since they are how members refer to options in help and error text.
+func localizeCommand(command *discordgo.ApplicationCommand) {
+	command.NameLocalizations = locales.Localizations(command.Name)
+	if command.Description != "" {
+		command.DescriptionLocalizations = locales.Localizations(command.Description)
+	}
+	localizeCommandOptions(command.Options)
+}
+
+func localizeCommandOptions(options []*discordgo.ApplicationCommandOption) {
+	for _, option := range options {
+		if option.Type == discordgo.ApplicationCommandOptionSubCommand || option.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			option.NameLocalizations = localizationsValue(locales.Localizations(option.Name))
+		}
+		option.DescriptionLocalizations = localizationsValue(locales.Localizations(option.Description))
+		for _, choice := range option.Choices {
+			choice.NameLocalizations = localizationsValue(locales.Localizations(choice.Name))
+		}
+		localizeCommandOptions(option.Options)
+	}
+}
+
+// Options store their localizations by value rather than by pointer.
+func localizationsValue(localizations *map[discordgo.Locale]string) map[discordgo.Locale]string {
+	if localizations == nil {
+		return nil
+	}
+	return *localizations
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+var subcommandConfigSyncMove = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "move",
+		Description: "Move a channel's sync to another channel",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "to",
+				Description:  "Channel to move the sync to",
+				ChannelTypes: syncChannelTypes,
+				Required:     true,
+			},
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "delete-old-messages",
+				Description: "Delete the synced messages in the old channel",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "leave-pointer",
+				Description: "Leave a message in the old channel linking to the new one. Defaults to true.",
+				Required:    false,
+			},
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleMoveSyncCommand),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+	},
+}
+
+func handleMoveSyncCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	// The old channel may have been deleted, so it is not required to exist.
+	fromChannelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		sendEphemeralResponse(session, interaction, localize(interaction, "Either channel or channel-id must be given."))
+		return
+	}
+	toChannelId := optionMap["to"].ChannelValue(nil).ID
+	deleteOldMessages := false
+	if opt, ok := optionMap["delete-old-messages"]; ok {
+		deleteOldMessages = opt.BoolValue()
+	}
+	leavePointer := true
+	if opt, ok := optionMap["leave-pointer"]; ok {
+		leavePointer = opt.BoolValue()
+	}
+
+	if fromChannelId == toChannelId {
+		sendEphemeralResponse(session, interaction, localize(interaction, "The sync is already in that channel."))
+		return
+	}
+
+	fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, fromChannelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if msg != "" {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+
+	// A channel can only sync each file once.
+	toChannelSyncs, err := appCtx.DB.GetGuildChannelSyncs(context.Background(), db.GetGuildChannelSyncsParams{
+		GuildID:   interaction.GuildID,
+		ChannelID: toChannelId,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	for _, channelSync := range toChannelSyncs {
+		if channelSync.FileToSyncUri == fileToSync.FileToSyncUri {
+			sendEphemeralResponse(session, interaction, localize(interaction, "<#%s> already syncs %s.", toChannelId, fileToSync.FileToSyncUri))
+			return
+		}
+	}
+
+	// Reposting every chunk can take longer than the interaction deadline.
+	err = sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	ctx := logger.WithContext(context.Background())
+
+	// Look up the posted messages before their records are removed.
+	chunks, err := appCtx.DB.GetFileContentChunks(ctx, fileToSync.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+
+	err = moveChannelSync(ctx, *appCtx, fileToSync.ID, toChannelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+
+	lines := []string{localize(interaction, "Moved sync of %s from <#%s> to <#%s>.", fileToSync.FileToSyncUri, fromChannelId, toChannelId)}
+	if fileToSync.FileContents == "" {
+		lines = append(lines, localize(interaction, "Nothing had been synced yet. Run /sync run to post the file."))
+	} else {
+		// Repost the last synced contents rather than fetching the file again.
+		// The moved sync goes after any files already in the new channel.
+		onProgress := throttledProgressResponse(session, interaction, syncProgressInterval)
+		movedFileToSync, err := appCtx.DB.GetGuildFileSync(ctx, db.GetGuildFileSyncParams{
+			GuildID:      interaction.GuildID,
+			FileToSyncID: fileToSync.ID,
+		})
+		if err == nil {
+			_, err = syncContentsToDiscordMessages(ctx, *appCtx, movedFileToSync, fileToSync.FileContents, onProgress)
+		}
+		if err != nil {
+			// Keep the old messages so the content is still readable somewhere.
+			lines = append(lines, localize(interaction, "Failed to post the messages: %s\nRun /sync run to try again. The old messages were kept.", err))
+			editResponse(session, interaction, truncateMessage(strings.Join(lines, "\n")))
+			return
+		}
+	}
+
+	if deleteOldMessages {
+		lines = append(lines, deleteSyncMessages(ctx, session, interaction, fileToSync, chunks))
+	}
+
+	// A deleted forum post leaves nowhere to put the pointer, since forum channels can't hold messages directly.
+	if leavePointer && !(deleteOldMessages && fileToSync.DiscordThreadSnowflake != "") {
+		pointerChannelId := fromChannelId
+		if fileToSync.DiscordThreadSnowflake != "" {
+			pointerChannelId = fileToSync.DiscordThreadSnowflake
+		}
+		pointer := fmt.Sprintf("This content has moved to <#%s>.", toChannelId)
+		_, err := session.ChannelMessageSend(pointerChannelId, pointer)
+		if err != nil {
+			logger.Warn().Err(err).Str("channel_id", pointerChannelId).Msg("Failed to leave pointer message.")
+			lines = append(lines, localize(interaction, "Failed to leave a message in the old channel."))
+		} else {
+			lines = append(lines, localize(interaction, "Left a message in the old channel linking to the new one."))
+		}
+	}
+
+	editResponse(session, interaction, truncateMessage(strings.Join(lines, "\n")))
+}
+
+// Points the sync at a new channel and forgets its old messages, so the next sync posts new ones.
+// The stored contents are cleared so a failed repost is retried by the next sync.
+func moveChannelSync(ctx context.Context, appCtx config.AppCtx, fileToSyncId int64, newChannelId string) error {
+	tx, err := appCtx.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := appCtx.DB.WithTx(tx)
+	if err := queries.RemoveFileContentChunks(ctx, fileToSyncId); err != nil {
+		return err
+	}
+	err = queries.MoveChannelSync(ctx, db.MoveChannelSyncParams{
+		NewChannelID: newChannelId,
+		FileToSyncID: fileToSyncId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+)
+
+// Channel types that a file can be synced to.
+var syncChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+	discordgo.ChannelTypeGuildForum,
+}
+
+// Builds the "channel" picker option. Pair with channelIdOption, since neither is required by discord;
+// resolveChannelOption requires one of them.
+func channelOption(channelTypes []discordgo.ChannelType) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionChannel,
+		Name:         "channel",
+		Description:  "Channel",
+		ChannelTypes: channelTypes,
+		Required:     false,
+	}
+}
+
+// Builds the "channel-id" option, which accepts a raw snowflake in place of the channel picker for scripted use.
+// Commands that act on existing syncs should enable autocomplete and use autocompleteSyncedChannels.
+func channelIdOption(autocomplete bool) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         "channel-id",
+		Description:  "Channel ID, for use in place of channel",
+		Required:     false,
+		Autocomplete: autocomplete,
+	}
+}
+
+// Builds the "file" option, which picks one of a channel's synced files when it has more than one.
+// Suggestions come from autocompleteSyncedChannels, and resolveFileSyncOption looks the file up.
+func fileOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionString,
+		Name:         "file",
+		Description:  "Synced file, if the channel has more than one",
+		Required:     false,
+		Autocomplete: true,
+	}
+}
+
+// Builds the "heading" option, which is shown above a file's first message to separate it from the file before.
+func headingOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "heading",
+		Description: "Text shown above the file's first message, to separate it from the file before",
+		Required:    false,
+		MaxLength:   maxHeadingLength,
+	}
+}
+
+// Builds the "attachment-mode" option, which picks whether a sync's file is posted as messages or as an attachment.
+func attachmentModeOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "attachment-mode",
+		Description: "Whether the file is posted as messages, or as an attachment once it is large",
+		Required:    false,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "Auto", Value: attachmentModeAuto},
+			{Name: "Always messages", Value: attachmentModeMessages},
+			{Name: "Always attachment", Value: attachmentModeAttachment},
+		},
+	}
+}
+
+// Gets the channel given by either the "channel" or "channel-id" option.
+// If the channel can not be used, a message explaining why is returned instead.
+func resolveChannelOption(session *discordgo.Session, interaction *discordgo.Interaction, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption, channelTypes []discordgo.ChannelType) (*discordgo.Channel, string, error) {
+	channelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		return nil, localize(interaction, "Either channel or channel-id must be given."), nil
+	}
+
+	if _, ok := optionMap["channel"]; ok {
+		resolved := interaction.ApplicationCommandData().Resolved
+		if resolved != nil && resolved.Channels[channelId] != nil && slices.Contains(channelTypes, resolved.Channels[channelId].Type) {
+			return resolved.Channels[channelId], "", nil
+		}
+	}
+
+	// Handle channel not existing within current guild.
+	channels, err := session.GuildChannels(interaction.GuildID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	channelIndex := slices.IndexFunc(channels, func(c *discordgo.Channel) bool {
+		return c.ID == channelId
+	})
+	if channelIndex == -1 {
+		return nil, localize(interaction, "Channel: '%s' does not exist in this guild.", channelId), nil
+	}
+
+	channel := channels[channelIndex]
+	if !slices.Contains(channelTypes, channel.Type) {
+		return nil, localize(interaction, "Channel <#%s> can not be used with this command.", channelId), nil
+	}
+
+	return channel, "", nil
+}
+
+// Gets the channel ID given by either the "channel" or "channel-id" option without checking that the channel exists.
+// Useful for looking up syncs of channels that have since been deleted.
+func channelIdFromOptions(optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption) (string, bool) {
+	if opt, ok := optionMap["channel"]; ok {
+		return opt.ChannelValue(nil).ID, true
+	}
+	if opt, ok := optionMap["channel-id"]; ok {
+		return opt.StringValue(), true
+	}
+	return "", false
+}
+
+// Gets the sync of the file given by the "file" option in the given channel. The option may be left out
+// when the channel only syncs one file. If the sync can't be found, a message explaining why is returned instead.
+func resolveFileSyncOption(ctx context.Context, appCtx config.AppCtx, interaction *discordgo.Interaction, optionMap map[string]*discordgo.ApplicationCommandInteractionDataOption, channelId string) (db.FilesToSync, string, error) {
+	channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(ctx, db.GetGuildChannelSyncsParams{
+		GuildID:   interaction.GuildID,
+		ChannelID: channelId,
+	})
+	if err != nil {
+		return db.FilesToSync{}, "", err
+	}
+	if len(channelSyncs) == 0 {
+		return db.FilesToSync{}, localize(interaction, "Channel <#%s> is not being synced.", channelId), nil
+	}
+
+	opt, ok := optionMap["file"]
+	if !ok {
+		if len(channelSyncs) > 1 {
+			return db.FilesToSync{}, localize(interaction, "<#%s> syncs %d files. Choose one with the file option.", channelId, len(channelSyncs)), nil
+		}
+		return channelSyncs[0], "", nil
+	}
+
+	// Suggestions fill in the sync's ID, but the file's URI can be typed out too.
+	file := opt.StringValue()
+	for _, fileSync := range channelSyncs {
+		if strconv.FormatInt(fileSync.ID, 10) == file || fileSync.FileToSyncUri == file {
+			return fileSync, "", nil
+		}
+	}
+	return db.FilesToSync{}, localize(interaction, "<#%s> does not sync %s.", channelId, file), nil
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+var subcommandConfigSyncPause = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "pause",
+		Description: "Stop a channel's sync from updating until it is resumed",
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleSetSyncPaused(true)),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+	},
+}
+
+var subcommandConfigSyncResume = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "resume",
+		Description: "Resume a paused sync",
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleSetSyncPaused(false)),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+	},
+}
+
+// Pause and resume only differ in the paused state they set.
+func handleSetSyncPaused(paused bool) InteractionHandler {
+	return func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+		// Build options map
+		options := commandOptions(interaction)
+		optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+		for _, opt := range options {
+			optionMap[opt.Name] = opt
+		}
+
+		channelId, ok := channelIdFromOptions(optionMap)
+		if !ok {
+			sendEphemeralResponse(session, interaction, localize(interaction, "Either channel or channel-id must be given."))
+			return
+		}
+
+		fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, channelId)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			sendErrorResponse(session, interaction)
+			return
+		}
+		if msg != "" {
+			sendEphemeralResponse(session, interaction, msg)
+			return
+		}
+
+		if fileToSync.Paused == paused {
+			msg = localize(interaction, "The sync of <#%s> is already paused.", channelId)
+			if !paused {
+				msg = localize(interaction, "The sync of <#%s> is not paused.", channelId)
+			}
+			sendEphemeralResponse(session, interaction, msg)
+			return
+		}
+
+		err = appCtx.DB.SetFileSyncPaused(context.Background(), db.SetFileSyncPausedParams{
+			Paused:       paused,
+			FileToSyncID: fileToSync.ID,
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			sendErrorResponse(session, interaction)
+			return
+		}
+
+		msg = localize(interaction, "Paused the sync of <#%s>. It will not be updated until it is resumed with /sync resume.", channelId)
+		if !paused {
+			msg = localize(interaction, "Resumed the sync of <#%s>. Run /sync run to catch up on changes made while it was paused.", channelId)
+		}
+		sendEphemeralResponse(session, interaction, msg)
+	}
+}
@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Custom ID is formatted as "sync:refresh:<channel-id>".
+	syncRefreshPrefix = "sync:refresh:"
+	// Minimum time between refreshes of the same channel, so readers can't hammer the source.
+	syncRefreshCooldown = time.Minute
+)
+
+// Last time each channel was refreshed from its refresh button.
+var (
+	syncRefreshTimes   = make(map[string]time.Time)
+	syncRefreshTimesMu sync.Mutex
+)
+
+// Records a refresh of the channel, returning when the next refresh is allowed if it is still cooling down.
+func startSyncRefreshCooldown(channelId string) (time.Time, bool) {
+	syncRefreshTimesMu.Lock()
+	defer syncRefreshTimesMu.Unlock()
+	if last, ok := syncRefreshTimes[channelId]; ok && time.Since(last) < syncRefreshCooldown {
+		return last.Add(syncRefreshCooldown), false
+	}
+	syncRefreshTimes[channelId] = time.Now()
+	return time.Time{}, true
+}
+
+// Syncs the channel the clicked refresh button belongs to. Anyone who can see the message may refresh it,
+// so it is not gated by the sync permissions.
+func handleSyncRefreshButton(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	channelId := strings.TrimPrefix(interaction.MessageComponentData().CustomID, syncRefreshPrefix)
+
+	if nextRefresh, ok := startSyncRefreshCooldown(channelId); !ok {
+		msg := localize(interaction, "This was refreshed recently. Try again <t:%d:R>.", nextRefresh.Unix())
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+
+	// Fetching and updating a large file can take longer than the interaction deadline.
+	err := sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(context.Background(), db.GetGuildChannelSyncsParams{
+		GuildID:   interaction.GuildID,
+		ChannelID: channelId,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+	if len(channelSyncs) == 0 {
+		editResponse(session, interaction, localize(interaction, "This content is no longer being synced."))
+		return
+	}
+
+	// The button refreshes every file in the channel, one after another so they stay in order.
+	results := syncAllFiles(logger.WithContext(context.Background()), *appCtx, channelSyncs)
+	var updated, paused int
+	for _, result := range results {
+		switch {
+		case errors.Is(result.err, ErrSyncPaused):
+			paused++
+		case result.err != nil:
+			err = result.err
+		case result.updated:
+			updated++
+		}
+	}
+	switch {
+	case paused == len(results):
+		editResponse(session, interaction, localize(interaction, "Updates to this content are paused."))
+	case err != nil:
+		editResponse(session, interaction, localize(interaction, "Failed to refresh. Please try again later or contact a server admin."))
+	case updated > 0:
+		editResponse(session, interaction, localize(interaction, "Refreshed to the latest version."))
+	default:
+		editResponse(session, interaction, localize(interaction, "Already up to date."))
+	}
+}
+
+// Adds or removes the refresh button on the sync's last existing chunk message to match its settings.
+func updateRefreshButton(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync) error {
+	chunkRows, err := appCtx.DB.GetFileContentChunks(ctx, fileToSync.ID)
+	if err != nil {
+		return err
+	}
+	if len(chunkRows) == 0 {
+		return nil
+	}
+
+	lastChunk := chunkRows[0]
+	for _, chunkRow := range chunkRows {
+		if chunkRow.ChunkNumber > lastChunk.ChunkNumber {
+			lastChunk = chunkRow
+		}
+	}
+
+	messageChannelId := fileToSync.DiscordChannelSnowflake
+	if fileToSync.DiscordThreadSnowflake != "" {
+		messageChannelId = fileToSync.DiscordThreadSnowflake
+	}
+
+	components := chunkMessageComponents(fileToSync, true)
+	_, err = appCtx.DiscordSession.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         lastChunk.DiscordMessageID,
+		Channel:    messageChannelId,
+		Components: &components,
+	})
+	return err
+}
@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	removeSyncConfirmPrefix = "remove-sync:confirm:"
+	removeSyncCancelID      = "remove-sync:cancel"
+)
+
+var subcommandConfigSyncRemove = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "remove",
+		Description: "Stop syncing a channel and remove its sync records.",
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "delete-messages",
+				Description: "Also delete the messages the bot posted in the channel",
+				Required:    false,
+			},
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleRemoveSyncCommand),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+		components: map[string]InteractionHandler{
+			removeSyncConfirmPrefix: requireSyncPermission(handleRemoveSyncButton),
+			removeSyncCancelID:      requireSyncPermission(handleRemoveSyncButton),
+		},
+	},
+}
+
+func handleRemoveSyncCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	channelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		sendEphemeralResponse(session, interaction, localize(interaction, "Either channel or channel-id must be given."))
+		return
+	}
+	deleteMessages := false
+	if opt, ok := optionMap["delete-messages"]; ok {
+		deleteMessages = opt.BoolValue()
+	}
+
+	// Make sure there is something to remove before asking for confirmation.
+	fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, channelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if msg != "" {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+
+	msg = localize(interaction, "Remove sync of %s from <#%s>?", fileToSync.FileToSyncUri, channelId)
+	if deleteMessages {
+		msg += "\n" + localize(interaction, "The synced messages in the channel will also be deleted.")
+	}
+
+	err = session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    localize(interaction, "Remove"),
+							Style:    discordgo.DangerButton,
+							CustomID: removeSyncConfirmPrefix + strconv.FormatInt(fileToSync.ID, 10) + ":" + strconv.FormatBool(deleteMessages),
+						},
+						discordgo.Button{
+							Label:    localize(interaction, "Cancel"),
+							Style:    discordgo.SecondaryButton,
+							CustomID: removeSyncCancelID,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+func handleRemoveSyncButton(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	customId := interaction.MessageComponentData().CustomID
+	if customId == removeSyncCancelID {
+		sendUpdateMessageResponse(session, interaction, localize(interaction, "Sync removal cancelled."))
+		return
+	}
+
+	// Custom ID is formatted as "remove-sync:confirm:<file-to-sync-id>:<delete-messages>"
+	args := strings.Split(strings.TrimPrefix(customId, removeSyncConfirmPrefix), ":")
+	if len(args) != 2 {
+		logger.Error().Str("custom_id", customId).Msg("Malformed component custom ID.")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	fileToSyncId, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		logger.Error().Err(err).Str("custom_id", customId).Msg("Malformed component custom ID.")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	deleteMessages, err := strconv.ParseBool(args[1])
+	if err != nil {
+		logger.Error().Err(err).Str("custom_id", customId).Msg("Malformed component custom ID.")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	fileToSync, err := appCtx.DB.GetGuildFileSync(context.Background(), db.GetGuildFileSyncParams{
+		GuildID:      interaction.GuildID,
+		FileToSyncID: fileToSyncId,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			sendUpdateMessageResponse(session, interaction, localize(interaction, "This sync has already been removed."))
+			return
+		}
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	// Look up the posted messages before their records are removed.
+	channelId := fileToSync.DiscordChannelSnowflake
+	chunks, err := appCtx.DB.GetFileContentChunks(context.Background(), fileToSync.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	err = RemoveChannelSync(logger.WithContext(context.Background()), *appCtx, fileToSync.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	msg := localize(interaction, "Removed sync of %s from <#%s>.", fileToSync.FileToSyncUri, channelId)
+	if deleteMessages {
+		msg += "\n" + deleteSyncMessages(logger.WithContext(context.Background()), session, interaction, fileToSync, chunks)
+	}
+
+	sendUpdateMessageResponse(session, interaction, msg)
+}
+
+// Removes a sync record along with its message chunk and GitHub repo records.
+func RemoveChannelSync(ctx context.Context, appCtx config.AppCtx, fileToSyncId int64) error {
+	logger := zerolog.Ctx(ctx)
+
+	tx, err := appCtx.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := removeChannelSyncRecords(ctx, appCtx.DB.WithTx(tx), fileToSyncId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	logger.Info().Int64("file_to_sync_id", fileToSyncId).Msg("Removed sync records.")
+	return nil
+}
+
+// Removes the records of a sync with queries, which should be part of a transaction.
+func removeChannelSyncRecords(ctx context.Context, queries *db.Queries, fileToSyncId int64) error {
+	if err := queries.RemoveFileContentChunks(ctx, fileToSyncId); err != nil {
+		return err
+	}
+	if err := queries.RemoveGithubRepoFile(ctx, fileToSyncId); err != nil {
+		return err
+	}
+	return queries.RemoveChannelSync(ctx, fileToSyncId)
+}
+
+// Deletes a sync's posted messages, or its forum post if it has one.
+// Returns a line describing the result for the member who asked.
+func deleteSyncMessages(ctx context.Context, session *discordgo.Session, interaction *discordgo.Interaction, fileToSync db.FilesToSync, chunks []db.GetFileContentChunksRow) string {
+	logger := zerolog.Ctx(ctx)
+
+	if fileToSync.DiscordThreadSnowflake != "" {
+		// Deleting the sync's forum post deletes all of its messages.
+		_, err := session.ChannelDelete(fileToSync.DiscordThreadSnowflake)
+		if err != nil {
+			logger.Warn().Err(err).Str("thread_id", fileToSync.DiscordThreadSnowflake).Msg("Failed to delete forum post.")
+			return localize(interaction, "Failed to delete the forum post.")
+		}
+		return localize(interaction, "Deleted the forum post.")
+	}
+
+	failed := 0
+	for _, chunk := range chunks {
+		err := session.ChannelMessageDelete(fileToSync.DiscordChannelSnowflake, chunk.DiscordMessageID)
+		if err != nil {
+			logger.Warn().Err(err).Str("message_id", chunk.DiscordMessageID).Msg("Failed to delete message chunk.")
+			failed++
+		}
+	}
+	return localize(interaction, "Deleted %d of %d messages.", len(chunks)-failed, len(chunks))
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Handles an interaction dispatched by the router. logger is tagged with the interaction's details.
+type InteractionHandler func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger)
+
+// Wraps a handler to run code before or after it, or to stop it from running.
+type Middleware func(next InteractionHandler) InteractionHandler
+
+// Dispatches interactions to the handler registered for them, so handlers only ever see
+// the interactions meant for them.
+type router struct {
+	appCtx *config.AppCtx
+	// Applied to every handler, outermost first.
+	middleware []Middleware
+	// Keyed by command path: the command's name followed by its subcommand group and subcommand, if any.
+	commands      map[string]InteractionHandler
+	autocompletes map[string]InteractionHandler
+	// Keyed by custom ID prefix.
+	components map[string]InteractionHandler
+	modals     map[string]InteractionHandler
+}
+
+func newRouter(appCtx *config.AppCtx, middleware ...Middleware) *router {
+	return &router{
+		appCtx:        appCtx,
+		middleware:    middleware,
+		commands:      make(map[string]InteractionHandler),
+		autocompletes: make(map[string]InteractionHandler),
+		components:    make(map[string]InteractionHandler),
+		modals:        make(map[string]InteractionHandler),
+	}
+}
+
+// Registers the handlers of a command and its subcommands.
+func (r *router) addCommand(config CommandConfig) {
+	r.addRoutes(config.info.Name, config.routes)
+	for _, subcommand := range config.subcommands {
+		r.addSubcommand(config.info.Name, subcommand)
+	}
+}
+
+func (r *router) addSubcommand(parentPath string, config SubcommandConfig) {
+	path := parentPath + " " + config.info.Name
+	r.addRoutes(path, config.routes)
+	for _, subcommand := range config.subcommands {
+		r.addSubcommand(path, subcommand)
+	}
+}
+
+func (r *router) addRoutes(path string, routes commandRoutes) {
+	if routes.handler != nil {
+		r.commands[path] = routes.handler
+	}
+	if routes.autocomplete != nil {
+		r.autocompletes[path] = routes.autocomplete
+	}
+	for prefix, handler := range routes.components {
+		r.components[prefix] = handler
+	}
+	for prefix, handler := range routes.modals {
+		r.modals[prefix] = handler
+	}
+}
+
+// Looks up the handler for an interaction, returning nil if there is none.
+func (r *router) route(interaction *discordgo.Interaction) InteractionHandler {
+	switch interaction.Type {
+	case discordgo.InteractionApplicationCommand:
+		path, _ := commandPath(interaction.ApplicationCommandData())
+		return r.commands[path]
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		path, _ := commandPath(interaction.ApplicationCommandData())
+		return r.autocompletes[path]
+	case discordgo.InteractionMessageComponent:
+		return routeByPrefix(r.components, interaction.MessageComponentData().CustomID)
+	case discordgo.InteractionModalSubmit:
+		return routeByPrefix(r.modals, interaction.ModalSubmitData().CustomID)
+	}
+	return nil
+}
+
+// Handles discord's InteractionCreate events.
+func (r *router) handle(session *discordgo.Session, event *discordgo.InteractionCreate) {
+	interaction := event.Interaction
+	handler := r.route(interaction)
+	if handler == nil {
+		log.Debug().Int("interaction_type", int(interaction.Type)).Msg("No handler for interaction.")
+		return
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	handler(session, interaction, r.appCtx, newInteractionLogger(interaction))
+}
+
+// Custom IDs are matched on their longest registered prefix, so a prefix can be shared by related components.
+func routeByPrefix(routes map[string]InteractionHandler, customId string) InteractionHandler {
+	var handler InteractionHandler
+	longest := -1
+	for prefix, h := range routes {
+		if strings.HasPrefix(customId, prefix) && len(prefix) > longest {
+			handler, longest = h, len(prefix)
+		}
+	}
+	return handler
+}
+
+// Gets the command's name along with its subcommand group and subcommand, separated by spaces,
+// and the options given to the innermost of them.
+func commandPath(data discordgo.ApplicationCommandInteractionData) (string, []*discordgo.ApplicationCommandInteractionDataOption) {
+	path := data.Name
+	options := data.Options
+	for len(options) > 0 && (options[0].Type == discordgo.ApplicationCommandOptionSubCommandGroup || options[0].Type == discordgo.ApplicationCommandOptionSubCommand) {
+		path += " " + options[0].Name
+		options = options[0].Options
+	}
+	return path, options
+}
+
+// Gets the options given to a command, or to its subcommand if it has one.
+func commandOptions(interaction *discordgo.Interaction) []*discordgo.ApplicationCommandInteractionDataOption {
+	_, options := commandPath(interaction.ApplicationCommandData())
+	return options
+}
+
+// Logs when a handler starts and how long it took. Autocompletes run on every keystroke, so they are not logged.
+func logInteraction(next InteractionHandler) InteractionHandler {
+	return func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+		if interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			next(session, interaction, appCtx, logger)
+			return
+		}
+
+		kind := "Command"
+		if interaction.Type == discordgo.InteractionMessageComponent || interaction.Type == discordgo.InteractionModalSubmit {
+			kind = "Component"
+		}
+		defer logExecutionTime(logger, kind+" finished executing.")()
+		logger.Info().Msg(kind + " started.")
+		next(session, interaction, appCtx, logger)
+	}
+}
+
+// Keeps a panicking handler from taking down the bot, and lets the member know something went wrong.
+func recoverPanics(next InteractionHandler) InteractionHandler {
+	return func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			logger.Error().Interface("panic", recovered).Str("stack", string(debug.Stack())).Msg("Recovered from panic in interaction handler.")
+
+			switch interaction.Type {
+			case discordgo.InteractionApplicationCommandAutocomplete:
+				sendAutocompleteResponse(session, interaction, nil)
+			default:
+				// The handler may have already acknowledged the interaction, in which case only an edit is accepted.
+				err := session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+					Type: discordgo.InteractionResponseChannelMessageWithSource,
+					Data: &discordgo.InteractionResponseData{
+						Content: localize(interaction, "Something went wrong. Please try again or contact bot owner."),
+						Flags:   discordgo.MessageFlagsEphemeral,
+					},
+				})
+				if err != nil {
+					editErrorResponse(session, interaction)
+				}
+			}
+		}()
+		next(session, interaction, appCtx, logger)
+	}
+}
+
+// Only runs the handler for members allowed to manage syncs.
+// Autocompletes get no suggestions instead, since the suggestions reveal the guild's syncs.
+func requireSyncPermission(next InteractionHandler) InteractionHandler {
+	return func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+		if interaction.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			allowed, err := hasSyncPermission(logger.WithContext(context.Background()), *appCtx, interaction)
+			if err != nil {
+				logger.Error().Err(err).Msg("")
+			}
+			if !allowed {
+				sendAutocompleteResponse(session, interaction, nil)
+				return
+			}
+			next(session, interaction, appCtx, logger)
+			return
+		}
+
+		if !ensureSyncPermission(session, interaction, appCtx, logger) {
+			return
+		}
+		next(session, interaction, appCtx, logger)
+	}
+}
+
+// Only runs the handler for members who can manage the guild.
+func requireManageServer(next InteractionHandler) InteractionHandler {
+	return func(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+		if interaction.Member == nil || interaction.Member.Permissions&(discordgo.PermissionManageServer|discordgo.PermissionAdministrator) == 0 {
+			logger.Info().Msg("Member is not allowed to manage the guild.")
+			sendEphemeralResponse(session, interaction, localize(interaction, "You need the Manage Server permission to change sync permissions."))
+			return
+		}
+		next(session, interaction, appCtx, logger)
+	}
+}
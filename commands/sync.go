@@ -2,90 +2,195 @@ package commands
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"slices"
+	"net/url"
+	"path"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/michaeldoylecs/discord-sync-bot/config"
 	"github.com/michaeldoylecs/discord-sync-bot/db"
 	"github.com/rs/zerolog"
 )
 
+// Parent of the sync management subcommands, e.g. /sync add.
 var commandConfigSync = CommandConfig{
 	info: &discordgo.ApplicationCommand{
-		Name:        "sync",
-		Description: "Update syncs",
+		Name:                     "sync",
+		Description:              "Manage this guild's channel syncs",
+		DefaultMemberPermissions: &manageSyncsPermission,
+		DMPermission:             &allowInDMs,
+	},
+	subcommands: []SubcommandConfig{
+		subcommandConfigSyncAdd,
+		subcommandConfigSyncEdit,
+		subcommandConfigSyncList,
+		subcommandConfigSyncMove,
+		subcommandConfigSyncPause,
+		subcommandConfigSyncRemove,
+		subcommandConfigSyncResume,
+		subcommandConfigSyncRun,
+		subcommandConfigSyncRunAll,
+		subcommandConfigSyncStatus,
+	},
+}
+
+var subcommandConfigSyncRun = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "run",
+		Description: "Update a channel's messages to match its files",
 		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
 			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "channel-id",
-				Description: "Channel ID",
-				Required:    true,
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "preview",
+				Description: "Show what would change without updating any messages",
+				Required:    false,
 			},
 		},
 	},
-	handler: func(discordSession *discordgo.Session, appCtx *config.AppCtx) {
-		discordSession.AddHandler(func(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-			if interaction.ApplicationCommandData().Name != "sync" {
-				return
-			}
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleSyncRunCommand),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+		// Refresh buttons on synced messages run the same sync as the command.
+		components: map[string]InteractionHandler{
+			syncRefreshPrefix: handleSyncRefreshButton,
+		},
+	},
+}
 
-			// Create logger with command relevant info
-			logger := newInteractionLogger(interaction.Interaction)
-			defer logExecutionTime(logger, "Command finished executing.")()
-			logger.Info().Msg("Command started.")
+func handleSyncRunCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Parse arguments
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+	isPreview := false
+	if opt, ok := optionMap["preview"]; ok {
+		isPreview = opt.BoolValue()
+	}
+	logger.Info().Interface("arguments", options).Msg("Command arguments parsed.")
 
-			// Parse arguments
-			options := interaction.ApplicationCommandData().Options
-			channelId := options[0].Value.(string)
-			logger.Info().Interface("arguments", options).Msg("Command arguments parsed.")
+	// Fetching and updating a large file can take longer than the interaction deadline,
+	// so acknowledge now and report progress by editing the response.
+	err := sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
 
-			// Handle channel not existing within current guild.
-			channels, err := session.GuildChannels(interaction.GuildID)
-			if err != nil {
-				logger.Error().Err(err).Msg("")
-			}
+	channel, msg, err := resolveChannelOption(session, interaction, optionMap, syncChannelTypes)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+	if channel == nil {
+		editResponse(session, interaction, msg)
+		return
+	}
+	channelId := channel.ID
 
-			if !slices.ContainsFunc(channels, func(c *discordgo.Channel) bool {
-				return c.ID == channelId
-			}) {
-				msg := fmt.Sprintf("Channel: '%s' does not exist in this guild.", channelId)
-
-				session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: msg,
-					},
-				})
-			}
+	// Without a file, every file in the channel is synced in order.
+	_, fileGiven := optionMap["file"]
+	if !fileGiven && !isPreview {
+		channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(context.Background(), db.GetGuildChannelSyncsParams{
+			GuildID:   interaction.GuildID,
+			ChannelID: channelId,
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			editErrorResponse(session, interaction)
+			return
+		}
+		if len(channelSyncs) > 1 {
+			results := syncAllFiles(logger.WithContext(context.Background()), *appCtx, channelSyncs)
+			editResponse(session, interaction, formatSyncAllSummary(interaction, results))
+			return
+		}
+	}
 
-			// Get file contents
-			fileToSync, err := appCtx.DB.GetGuildChannelSync(context.Background(), db.GetGuildChannelSyncParams{
-				GuildID:   interaction.GuildID,
-				ChannelID: channelId,
-			})
-			if err != nil {
-				logger.Error().Err(err).Msg("")
-				sendErrorResponse(session, interaction.Interaction)
-				return
-			}
+	fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, channelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+	if msg != "" {
+		editResponse(session, interaction, msg)
+		return
+	}
 
-			oldFileContents := fileToSync.FileContents
-			fileUri := fileToSync.FileToSyncUri
+	if fileToSync.Paused && !isPreview {
+		editResponse(session, interaction, localize(interaction, "Skipped <#%s> because its sync is paused. Use /sync resume to resume it.", channelId))
+		return
+	}
 
-			err = SyncFileToDiscordMessages(logger.WithContext(context.Background()), *appCtx, interaction.GuildID, channelId, fileUri, oldFileContents)
-			if err != nil {
-				logger.Error().Err(err).Msg("")
-				sendErrorResponse(session, interaction.Interaction)
-			}
+	fileUri := fileToSync.FileToSyncUri
 
-			// Respond to command
-			msg := fmt.Sprintf("Synced file to <#%s>", channelId)
-			sendEphemeralResponse(session, interaction.Interaction, msg)
-		})
-	},
+	if isPreview {
+		msg, err := previewFileSync(logger.WithContext(context.Background()), *appCtx, interaction, fileToSync)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			editErrorResponse(session, interaction)
+			return
+		}
+		editResponse(session, interaction, msg)
+		return
+	}
+
+	editResponse(session, interaction, localize(interaction, "Fetching %s...", fileUri))
+	onProgress := throttledProgressResponse(session, interaction, syncProgressInterval)
+	result, err := SyncFileToDiscordMessages(logger.WithContext(context.Background()), *appCtx, interaction.GuildID, fileToSync.ID, onProgress)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editResponse(session, interaction, truncateMessage(localize(interaction, "Failed to sync file to <#%s>: %s", channelId, err)))
+		return
+	}
+
+	// Respond to command
+	if result.Updated {
+		msg = localize(interaction, "Synced file to <#%s>", channelId) + "\n" +
+			localize(interaction, "%d messages edited, %d sent, %d deleted, %d unchanged.", result.Edited, result.Sent, result.Deleted, result.Unchanged)
+		if result.Reposted > 0 {
+			msg += "\n" + localize(interaction, "Reposted %d messages of the files after it to keep the channel in order.", result.Reposted)
+		}
+	} else {
+		msg = localize(interaction, "<#%s> is already up to date.", channelId)
+	}
+	editResponse(session, interaction, msg)
+}
+
+// Minimum time between progress edits of a sync command's response, to stay clear of rate limits.
+const syncProgressInterval = 2 * time.Second
+
+// Receives human readable progress updates while a file is synced.
+// format is English text with fmt verbs, so it can be looked up in the locale catalogs.
+type SyncProgressFunc func(format string, args ...interface{})
+
+// Returned when syncing a file whose sync is paused.
+var ErrSyncPaused = errors.New("sync is paused")
+
+// Reports progress by editing the interaction's response, skipping updates that arrive within interval of the last edit.
+func throttledProgressResponse(session *discordgo.Session, interaction *discordgo.Interaction, interval time.Duration) SyncProgressFunc {
+	var lastEdit time.Time
+	return func(format string, args ...interface{}) {
+		if time.Since(lastEdit) < interval {
+			return
+		}
+		lastEdit = time.Now()
+		editResponse(session, interaction, localize(interaction, format, args...))
+	}
 }
 
 func makeInt32Range(min int32, max int32) []int32 {
@@ -96,80 +201,340 @@ func makeInt32Range(min int32, max int32) []int32 {
 	return l
 }
 
-func chunkContents(contents string, maxChunkSize int) []string {
-	chunks := make([]string, 0, len(contents)/maxChunkSize+1)
-	remainder := contents
-	for len(remainder) > maxChunkSize {
-		cursor := 1950
-		for remainder[cursor] != '\n' && cursor >= 0 {
-			cursor--
+// Pairs each of the new content chunks with the existing message that should hold it, given the hash of each
+// chunk's message content, and returns the hash of that message's current content alongside it.
+// Messages can't be inserted between others, so the chunks fill the kept messages in order and any left over
+// are sent after them with an empty id. Messages whose content is gone are deleted rather than edited, so removing
+// a chunk doesn't shift the chunks after it, while an inserted chunk shifts those after it down by one message.
+// Of the plans that do this, the one that edits, sends, and deletes the fewest messages is chosen.
+// If keepFirst, the first message is never deleted, such as when it starts the sync's forum post, even if there
+// are no chunks left for it to hold.
+// Messages that are deleted are returned as excess.
+func planChunkMessages(chunkHashes []string, existingChunks []db.GetFileContentChunksRow, keepFirst bool) ([]string, []string, []string) {
+	n, k := len(existingChunks), len(chunkHashes)
+
+	// cost[i][j] is the number of messages touched to sync chunkHashes[j:] to existingChunks[i:].
+	cost := make([][]int, n+1)
+	for i := range cost {
+		cost[i] = make([]int, k+1)
+	}
+	for i := n; i >= 0; i-- {
+		for j := k; j >= 0; j-- {
+			switch {
+			case i == n:
+				cost[i][j] = k - j
+			case j == k:
+				cost[i][j] = n - i
+			default:
+				cost[i][j] = cost[i+1][j+1]
+				if existingChunks[i].ContentHash != chunkHashes[j] {
+					cost[i][j]++
+				}
+				if !(keepFirst && i == 0) && cost[i+1][j]+1 < cost[i][j] {
+					cost[i][j] = cost[i+1][j] + 1
+				}
+			}
+		}
+	}
+
+	msgIds := make([]string, k)
+	msgHashes := make([]string, k)
+	excessMsgIds := make([]string, 0)
+	i, j := 0, 0
+	for i < n && j < k {
+		keep := cost[i+1][j+1]
+		if existingChunks[i].ContentHash != chunkHashes[j] {
+			keep++
+		}
+		if keep == cost[i][j] {
+			msgIds[j] = existingChunks[i].DiscordMessageID
+			msgHashes[j] = existingChunks[i].ContentHash
+			j++
+		} else {
+			excessMsgIds = append(excessMsgIds, existingChunks[i].DiscordMessageID)
 		}
-		chunks = append(chunks, remainder[:cursor])
-		remainder = remainder[cursor+1:]
+		i++
 	}
-	if len(remainder) > 0 {
-		chunks = append(chunks, remainder)
+	for ; i < n; i++ {
+		// With no chunks left to hold, the first message is kept as it is rather than deleted.
+		if keepFirst && i == 0 {
+			continue
+		}
+		excessMsgIds = append(excessMsgIds, existingChunks[i].DiscordMessageID)
 	}
-	return chunks
+	return msgIds, msgHashes, excessMsgIds
 }
 
-func SyncFileToDiscordMessages(ctx context.Context, appCtx config.AppCtx, guildId string, channelId string, fileUrl string, prevFileContents string) error {
+// Maximum length of a file's heading. A heading this long still fits within discord's 2000 character
+// message limit alongside a chunk of maxChunkLength bytes.
+const maxHeadingLength = 48
+
+// What a sync did to a channel's messages.
+type SyncResult struct {
+	// Whether the file had changed since it was last synced.
+	Updated bool
+	// The file's own messages edited, sent, and deleted.
+	Edited  int
+	Sent    int
+	Deleted int
+	// Messages left alone because their content had not changed.
+	Unchanged int
+	// Messages of the files after it in the channel that were deleted and posted again below it,
+	// so the channel's files stay in order.
+	Reposted int
+}
+
+// Number of the file's own messages the sync edited, sent, or deleted.
+func (r SyncResult) Touched() int {
+	return r.Edited + r.Sent + r.Deleted
+}
+
+// Syncs a file to its channel's messages. Returns what was done to the messages.
+// The outcome is recorded on the sync record for /sync status. onProgress may be nil.
+func SyncFileToDiscordMessages(ctx context.Context, appCtx config.AppCtx, guildId string, fileToSyncId int64, onProgress SyncProgressFunc) (SyncResult, error) {
+	logger := zerolog.Ctx(ctx)
+	if onProgress == nil {
+		onProgress = func(string, ...interface{}) {}
+	}
+
+	fileToSync, err := appCtx.DB.GetGuildFileSync(ctx, db.GetGuildFileSyncParams{
+		GuildID:      guildId,
+		FileToSyncID: fileToSyncId,
+	})
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	// Paused syncs keep their messages as they are until resumed.
+	if fileToSync.Paused {
+		logger.Info().Str("channel_id", fileToSync.DiscordChannelSnowflake).Msg("Skipped paused sync.")
+		return SyncResult{}, ErrSyncPaused
+	}
+
+	var result SyncResult
+	fileContents, httpStatus, err := fetchFileContents(ctx, fileToSync.FileToSyncUri)
+	if err == nil {
+		onProgress("Fetched %s.", fileToSync.FileToSyncUri)
+		result, err = syncContentsToDiscordMessages(ctx, appCtx, fileToSync, fileContents, onProgress)
+	}
+
+	// Record sync attempt
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	}
+	recordErr := appCtx.DB.SetFileSyncAttempt(context.Background(), db.SetFileSyncAttemptParams{
+		LastError:      lastError,
+		LastHttpStatus: pgtype.Int4{Int32: int32(httpStatus), Valid: httpStatus != 0},
+		FileToSyncID:   fileToSync.ID,
+	})
+	if recordErr != nil {
+		logger.Error().Err(recordErr).Msg("Failed to record sync attempt.")
+	}
+
+	return result, err
+}
+
+// GETs the file at fileUrl. Returns the file contents and the HTTP status code, which is 0 if no response was received.
+func fetchFileContents(ctx context.Context, fileUrl string) (string, int, error) {
 	logger := zerolog.Ctx(ctx)
-	session := appCtx.DiscordSession
 
 	fileContentsResponse, err := http.Get(fileUrl)
 	if err != nil {
 		logger.Error().Err(err)
-		return err
+		return "", 0, err
 	}
+	defer fileContentsResponse.Body.Close()
 	if fileContentsResponse.StatusCode != http.StatusOK {
-		logger.Warn().Str("file_uri", fileUrl).Msg("Failed to GET file.")
-		return err
+		logger.Warn().Str("file_uri", fileUrl).Int("status_code", fileContentsResponse.StatusCode).Msg("Failed to GET file.")
+		return "", fileContentsResponse.StatusCode, fmt.Errorf("failed to GET file: %s", fileContentsResponse.Status)
 	}
 	fileBytes, err := io.ReadAll(fileContentsResponse.Body)
 	if err != nil {
 		logger.Error().Err(err)
-		return err
+		return "", fileContentsResponse.StatusCode, err
 	}
-	fileContents := string(fileBytes)
+
+	return string(fileBytes), fileContentsResponse.StatusCode, nil
+}
+
+// Updates the file's messages to hold fileContents, unless they already do.
+func syncContentsToDiscordMessages(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync, fileContents string, onProgress SyncProgressFunc) (SyncResult, error) {
+	logger := zerolog.Ctx(ctx)
 
 	// Compare current file contents with previously synced contents.
-	if prevFileContents == fileContents {
+	if fileToSync.FileContents == fileContents {
 		// Respond that messages are already in-sync
 		logger.Info().Msg("Files already match.")
-		return nil
+		err := appCtx.DB.SetFileSyncedAt(context.Background(), fileToSync.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to record sync time.")
+			return SyncResult{}, err
+		}
+		return SyncResult{}, nil
 	}
 
-	// Chunk the file contents to fit within discord message limits.
-	contentChunks := chunkContents(fileContents, 1950)
+	result := SyncResult{Updated: true}
+	appended, err := postFileChunks(ctx, appCtx, fileToSync, fileContents, &result, onProgress)
+	if err != nil {
+		return result, err
+	}
+
+	// Update file contents in db
+	err = appCtx.DB.SetFileSyncContents(context.Background(), db.SetFileSyncContentsParams{
+		FileContents: fileContents,
+		ContentHash:  hashContents(fileContents),
+		FileToSyncID: fileToSync.ID,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to store synced file contents.")
+		return result, err
+	}
+
+	// Record successful sync time
+	err = appCtx.DB.SetFileSyncedAt(context.Background(), fileToSync.ID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to record sync time.")
+		return result, err
+	}
+
+	// New messages can only be added to the bottom of a channel, below any files that come after this one.
+	// Those files are posted again underneath so every file's messages stay together and in order.
+	if appended {
+		err = repostFollowingFiles(ctx, appCtx, fileToSync, &result)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			return result, err
+		}
+	}
+
+	logger.Info().
+		Int("messages_edited", result.Edited).
+		Int("messages_sent", result.Sent).
+		Int("messages_deleted", result.Deleted).
+		Int("messages_unchanged", result.Unchanged).
+		Int("messages_reposted", result.Reposted).
+		Msg("Synced file.")
+	return result, nil
+}
+
+// Edits the file's existing chunk messages to hold fileContents, sending new messages or deleting
+// leftover ones as needed. A file posted as an attachment is held by a single message that summarizes it.
+// Messages whose content is unchanged are not edited. The messages touched are
+// counted in result. Returns whether any messages were added to the bottom of the channel itself,
+// rather than to the file's forum post.
+func postFileChunks(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync, fileContents string, result *SyncResult, onProgress SyncProgressFunc) (bool, error) {
+	logger := zerolog.Ctx(ctx)
+	session := appCtx.DiscordSession
+	channelId := fileToSync.DiscordChannelSnowflake
+
+	// Messages synced to a forum channel live in the sync's forum post.
+	messageChannelId := channelId
+	if fileToSync.DiscordThreadSnowflake != "" {
+		messageChannelId = fileToSync.DiscordThreadSnowflake
+	}
+
+	// Chunk the file contents to fit within discord message limits, or summarize them above an attachment.
+	contents, contentHashes, attached := fileMessageContents(fileToSync, fileContents)
 
 	// Get current content chunks if they exist in db
-	existingMessageChunkRows, err := appCtx.DB.GetFileContentChunks(context.Background(), channelId)
+	existingMessageChunkRows, err := appCtx.DB.GetFileContentChunks(context.Background(), fileToSync.ID)
 	if err != nil {
 		logger.Error().Err(err)
-		return err
+		return false, err
 	}
 
 	// Associate existing message chunk ids with new chunks to update instead of making new mesages
-	msg_ids := make([]string, len(contentChunks))
-	for i := range existingMessageChunkRows {
-		if i > len(msg_ids) {
-			break
+	msg_ids, msgHashes, excessMsgIds := planChunkMessages(contentHashes, existingMessageChunkRows, fileToSync.DiscordThreadSnowflake != "")
+
+	// Moving the refresh button to a new last message means editing both the old and new last message,
+	// even if their content is unchanged.
+	oldLastMsgId := ""
+	if len(existingMessageChunkRows) > 0 {
+		oldLastMsgId = existingMessageChunkRows[len(existingMessageChunkRows)-1].DiscordMessageID
+	}
+	lastChunk := len(contents) - 1
+
+	// Forum channels can't hold messages directly, so start a forum post with the first chunk.
+	startedForumPost := false
+	if fileToSync.DiscordThreadSnowflake == "" && len(existingMessageChunkRows) == 0 && len(contents) > 0 {
+		channel, err := session.Channel(channelId)
+		if err != nil {
+			logger.Error().Err(err).Msg("")
+			return false, err
+		}
+		if channel.Type == discordgo.ChannelTypeGuildForum {
+			thread, err := session.ForumThreadStartComplex(channelId, &discordgo.ThreadStart{
+				Name: forumPostName(fileToSync.FileToSyncUri),
+			}, &discordgo.MessageSend{
+				Content:    contents[0],
+				Flags:      chunkMessageFlags(fileToSync),
+				Components: chunkMessageComponents(fileToSync, len(contents) == 1),
+				Files:      attachmentFiles(fileToSync, fileContents, attached),
+			})
+			if err != nil {
+				logger.Error().Err(err).Msg("")
+				return false, err
+			}
+			logger.Info().Str("thread_id", thread.ID).Msg("Started forum post.")
+
+			err = appCtx.DB.SetFileSyncThread(context.Background(), db.SetFileSyncThreadParams{
+				ThreadID:     thread.ID,
+				FileToSyncID: fileToSync.ID,
+			})
+			if err != nil {
+				logger.Error().Err(err).Msg("")
+				return false, err
+			}
+
+			// A forum post's starting message shares the post's ID.
+			messageChannelId = thread.ID
+			msg_ids[0] = thread.ID
+			msgHashes[0] = contentHashes[0]
+			startedForumPost = true
+			result.Sent++
+			pinChunkMessage(ctx, session, fileToSync, thread.ID, thread.ID)
 		}
-		chunk_index := existingMessageChunkRows[i].ChunkNumber - 1
-		chunk_message_id := existingMessageChunkRows[i].DiscordMessageID
-		msg_ids[chunk_index] = chunk_message_id
 	}
 
 	// Send discord messages with the chunks.
 	logger.Info().Msg("Attempting to send channel messages...")
-	for i, chunk := range contentChunks {
+	appended := false
+	for i := range contents {
+		if i > 0 {
+			onProgress("%d/%d chunks updated.", i, len(contents))
+		}
+		if i == 0 && startedForumPost {
+			continue
+		}
+
+		content := contents[i]
+		contentHash := contentHashes[i]
+		// Only the last chunk holds the refresh button, so clear it from the others in case the chunk count changed.
+		components := chunkMessageComponents(fileToSync, i == lastChunk)
+		movesRefreshButton := fileToSync.RefreshButton && (msg_ids[i] == oldLastMsgId) != (i == lastChunk)
+
+		// Leave unchanged messages alone to save on rate limits.
+		if msg_ids[i] != "" && msgHashes[i] == contentHash && !movesRefreshButton {
+			result.Unchanged++
+			continue
+		}
+
 		// Update existing message
 		if msg_ids[i] != "" {
-			msg, err := session.ChannelMessageEdit(channelId, msg_ids[i], chunk)
+			// Listing no attachments to keep drops the one the message has, such as the file before it changed,
+			// or the file itself when the sync has switched to posting messages.
+			msg, err := session.ChannelMessageEditComplex(&discordgo.MessageEdit{
+				ID:          msg_ids[i],
+				Channel:     messageChannelId,
+				Content:     &content,
+				Components:  &components,
+				Files:       attachmentFiles(fileToSync, fileContents, attached),
+				Attachments: &[]*discordgo.MessageAttachment{},
+			})
 			if err != nil {
 				logger.Error().Err(err)
-				return err
+				return false, err
 			} else {
 				logger.Info().
 					Str("message_channel_id", msg.ChannelID).
@@ -177,14 +542,21 @@ func SyncFileToDiscordMessages(ctx context.Context, appCtx config.AppCtx, guildI
 					Int("message_chunk_num", i+1).
 					Msg("Updated message chunk.")
 			}
+			msgHashes[i] = contentHash
+			result.Edited++
 			continue
 		}
 
 		// Send new message
-		msg, err := session.ChannelMessageSend(channelId, chunk)
+		msg, err := session.ChannelMessageSendComplex(messageChannelId, &discordgo.MessageSend{
+			Content:    content,
+			Flags:      chunkMessageFlags(fileToSync),
+			Components: components,
+			Files:      attachmentFiles(fileToSync, fileContents, attached),
+		})
 		if err != nil {
 			logger.Error().Err(err)
-			return err
+			return false, err
 		} else {
 			logger.Info().
 				Str("message_channel_id", msg.ChannelID).
@@ -193,47 +565,160 @@ func SyncFileToDiscordMessages(ctx context.Context, appCtx config.AppCtx, guildI
 				Msg("Sent new message chunk.")
 		}
 		msg_ids[i] = msg.ID
+		msgHashes[i] = contentHash
+		result.Sent++
+		appended = appended || messageChannelId == channelId
+		pinChunkMessage(ctx, session, fileToSync, messageChannelId, msg.ID)
 	}
 
 	// Remove excess pre-existing messages
-	if len(existingMessageChunkRows) > len(msg_ids) {
-		for i := range existingMessageChunkRows[len(msg_ids):] {
-			msg_id := existingMessageChunkRows[i].DiscordMessageID
-			err := session.ChannelMessageDelete(channelId, msg_id)
-			if err != nil {
-				logger.Warn().Err(err).Msg("")
-			}
+	for _, msg_id := range excessMsgIds {
+		err := session.ChannelMessageDelete(messageChannelId, msg_id)
+		if err != nil {
+			logger.Warn().Err(err).Msg("")
+			continue
 		}
+		result.Deleted++
 	}
-
-	// Get File db foreign key
-	fileToSync, err := appCtx.DB.GetChannelSync(context.Background(), fileUrl)
-	if err != nil {
-		logger.Error().Err(err).Msg("")
-		return err
+	if len(excessMsgIds) > 0 {
+		err = appCtx.DB.RemoveFileContentChunkMessages(context.Background(), excessMsgIds)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to remove deleted message chunks.")
+			return false, err
+		}
 	}
-	fileFK := fileToSync.ID
 
 	// Update database with content chunk info
 	_, err = appCtx.DB.AddFileContentChunks(context.Background(), db.AddFileContentChunksParams{
-		FilesToSyncFk:     fileFK,
+		FilesToSyncFk:     fileToSync.ID,
 		ChunkNumbers:      makeInt32Range(1, int32(len(msg_ids))),
 		DiscordMessageIds: msg_ids,
+		ContentHashes:     msgHashes,
 	})
 	if err != nil {
 		logger.Error().Err(err)
-		return err
+		return false, err
 	}
 
-	// Update file contents in db
-	err = appCtx.DB.SetFileSyncContents(context.Background(), db.SetFileSyncContentsParams{
-		FileContents: fileContents,
-		ChannelID:    channelId,
+	return appended, nil
+}
+
+// Deletes and posts again the messages of every file after fileToSync in its channel, from their last synced contents.
+// Files with a forum post of their own are left alone, as are paused files, which keep their messages as they are
+// until resumed. The messages posted again are counted in result as reposted.
+func repostFollowingFiles(ctx context.Context, appCtx config.AppCtx, fileToSync db.FilesToSync, result *SyncResult) error {
+	logger := zerolog.Ctx(ctx)
+
+	channelSyncs, err := appCtx.DB.GetGuildChannelSyncs(ctx, db.GetGuildChannelSyncsParams{
+		GuildID:   fileToSync.DiscordGuildSnowflake,
+		ChannelID: fileToSync.DiscordChannelSnowflake,
 	})
 	if err != nil {
-		logger.Error().Err(err)
 		return err
 	}
 
+	following := false
+	for _, channelSync := range channelSyncs {
+		if channelSync.ID == fileToSync.ID {
+			following = true
+			continue
+		}
+		if !following || channelSync.DiscordThreadSnowflake != "" {
+			continue
+		}
+		if channelSync.Paused {
+			logger.Info().Int64("file_to_sync_id", channelSync.ID).Msg("Skipped reposting paused file.")
+			continue
+		}
+
+		chunks, err := appCtx.DB.GetFileContentChunks(ctx, channelSync.ID)
+		if err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			continue
+		}
+		for _, chunk := range chunks {
+			err := appCtx.DiscordSession.ChannelMessageDelete(channelSync.DiscordChannelSnowflake, chunk.DiscordMessageID)
+			if err != nil {
+				logger.Warn().Err(err).Str("message_id", chunk.DiscordMessageID).Msg("Failed to delete message chunk.")
+			}
+		}
+		if err := appCtx.DB.RemoveFileContentChunks(ctx, channelSync.ID); err != nil {
+			return err
+		}
+
+		var reposted SyncResult
+		_, err = postFileChunks(ctx, appCtx, channelSync, channelSync.FileContents, &reposted, func(string, ...interface{}) {})
+		result.Reposted += reposted.Sent
+		if err != nil {
+			return err
+		}
+		logger.Info().Int64("file_to_sync_id", channelSync.ID).Int("message_count", len(chunks)).Msg("Reposted following file.")
+	}
 	return nil
 }
+
+// Gets the content of a file's chunk message. The first chunk is headed by the file's heading, if it has one.
+func chunkMessageContent(fileToSync db.FilesToSync, contentChunks []string, i int) string {
+	if i == 0 && fileToSync.Heading != "" {
+		return fileToSync.Heading + "\n" + contentChunks[0]
+	}
+	return contentChunks[i]
+}
+
+// Gets the flags a sync's chunk messages are sent with. Editing a message keeps its flags.
+func chunkMessageFlags(fileToSync db.FilesToSync) discordgo.MessageFlags {
+	if fileToSync.SuppressEmbeds {
+		return discordgo.MessageFlagsSuppressEmbeds
+	}
+	return 0
+}
+
+// Gets the components for a sync's chunk message. The last chunk holds the refresh button if the sync has one.
+func chunkMessageComponents(fileToSync db.FilesToSync, isLastChunk bool) []discordgo.MessageComponent {
+	if !isLastChunk || !fileToSync.RefreshButton {
+		return []discordgo.MessageComponent{}
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Refresh",
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔄"},
+					Style:    discordgo.SecondaryButton,
+					CustomID: syncRefreshPrefix + fileToSync.DiscordChannelSnowflake,
+				},
+			},
+		},
+	}
+}
+
+// Pins a newly posted chunk message if the sync pins its messages.
+// Failing to pin, such as when the channel is at discord's pin limit, does not fail the sync.
+func pinChunkMessage(ctx context.Context, session *discordgo.Session, fileToSync db.FilesToSync, channelId string, messageId string) {
+	if !fileToSync.PinMessages {
+		return
+	}
+	err := session.ChannelMessagePin(channelId, messageId)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("message_id", messageId).Msg("Failed to pin message chunk.")
+	}
+}
+
+// Names a forum post after the synced file, within discord's 100 character limit.
+func forumPostName(fileUrl string) string {
+	name := fileUrl
+	if parsedUrl, err := url.Parse(fileUrl); err == nil && path.Base(parsedUrl.Path) != "/" && path.Base(parsedUrl.Path) != "." {
+		name = path.Base(parsedUrl.Path)
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+func hashContents(contents string) string {
+	hash := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(hash[:])
+}
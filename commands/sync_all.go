@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+// Maximum number of files synced at the same time by /sync run-all.
+const syncAllConcurrency = 4
+
+var subcommandConfigSyncRunAll = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "run-all",
+		Description: "Update every sync in this guild",
+	},
+	routes: commandRoutes{
+		handler: requireSyncPermission(handleSyncAllCommand),
+	},
+}
+
+func handleSyncAllCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Syncing many files can take longer than the interaction deadline.
+	err := sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	fileSyncs, err := appCtx.DB.GetGuildSyncs(context.Background(), interaction.GuildID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+
+	if len(fileSyncs) == 0 {
+		editResponse(session, interaction, localize(interaction, "No channels are being synced in this guild."))
+		return
+	}
+
+	results := syncAllFiles(logger.WithContext(context.Background()), *appCtx, fileSyncs)
+	editResponse(session, interaction, formatSyncAllSummary(interaction, results))
+}
+
+type syncAllResult struct {
+	channelId string
+	fileUri   string
+	updated   bool
+	touched   int
+	reposted  int
+	err       error
+}
+
+// Syncs each file, running at most syncAllConcurrency channels at a time.
+// Files in the same channel are synced one after another, in order, since syncing a file can repost the files after it.
+// Results are returned in the same order as fileSyncs.
+func syncAllFiles(ctx context.Context, appCtx config.AppCtx, fileSyncs []db.FilesToSync) []syncAllResult {
+	logger := zerolog.Ctx(ctx)
+
+	channelIds := make([]string, 0)
+	channelFiles := make(map[string][]int)
+	for i, fileSync := range fileSyncs {
+		channelId := fileSync.DiscordChannelSnowflake
+		if _, ok := channelFiles[channelId]; !ok {
+			channelIds = append(channelIds, channelId)
+		}
+		channelFiles[channelId] = append(channelFiles[channelId], i)
+	}
+
+	results := make([]syncAllResult, len(fileSyncs))
+	semaphore := make(chan struct{}, syncAllConcurrency)
+	var wg sync.WaitGroup
+	for _, channelId := range channelIds {
+		wg.Add(1)
+		go func(fileIndexes []int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			for _, i := range fileIndexes {
+				fileSync := fileSyncs[i]
+				fileLogger := logger.With().
+					Str("channel_id", fileSync.DiscordChannelSnowflake).
+					Str("file_uri", fileSync.FileToSyncUri).
+					Logger()
+				result, err := SyncFileToDiscordMessages(
+					fileLogger.WithContext(ctx),
+					appCtx,
+					fileSync.DiscordGuildSnowflake,
+					fileSync.ID,
+					nil,
+				)
+				if err != nil && !errors.Is(err, ErrSyncPaused) {
+					fileLogger.Error().Err(err).Msg("Failed to sync file.")
+				}
+				results[i] = syncAllResult{
+					channelId: fileSync.DiscordChannelSnowflake,
+					fileUri:   fileSync.FileToSyncUri,
+					updated:   result.Updated,
+					touched:   result.Touched(),
+					reposted:  result.Reposted,
+					err:       err,
+				}
+			}
+		}(channelFiles[channelId])
+	}
+	wg.Wait()
+
+	return results
+}
+
+func formatSyncAllSummary(interaction *discordgo.Interaction, results []syncAllResult) string {
+	// Files are only named for channels that sync more than one.
+	channelFileCounts := make(map[string]int)
+	for _, result := range results {
+		channelFileCounts[result.channelId]++
+	}
+	label := func(result syncAllResult) string {
+		if channelFileCounts[result.channelId] > 1 {
+			return fmt.Sprintf("<#%s> (%s)", result.channelId, result.fileUri)
+		}
+		return fmt.Sprintf("<#%s>", result.channelId)
+	}
+
+	var updated, upToDate, paused, failed []string
+	touched, reposted := 0, 0
+	for _, result := range results {
+		touched += result.touched
+		reposted += result.reposted
+		switch {
+		case errors.Is(result.err, ErrSyncPaused):
+			paused = append(paused, label(result))
+		case result.err != nil:
+			failed = append(failed, fmt.Sprintf("%s: %s", label(result), result.err))
+		case result.updated:
+			updated = append(updated, label(result))
+		default:
+			upToDate = append(upToDate, label(result))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(localize(interaction, "Synced %d files, touching %d messages.", len(results), touched))
+	if reposted > 0 {
+		sb.WriteString(" " + localize(interaction, "Reposted %d messages of later files to keep their channels in order.", reposted))
+	}
+	if len(updated) > 0 {
+		fmt.Fprintf(&sb, "\n**%s**\n%s", localize(interaction, "Updated (%d)", len(updated)), strings.Join(updated, " "))
+	}
+	if len(upToDate) > 0 {
+		fmt.Fprintf(&sb, "\n**%s**\n%s", localize(interaction, "Already up to date (%d)", len(upToDate)), strings.Join(upToDate, " "))
+	}
+	if len(paused) > 0 {
+		fmt.Fprintf(&sb, "\n**%s**\n%s", localize(interaction, "Skipped because paused (%d)", len(paused)), strings.Join(paused, " "))
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(&sb, "\n**%s**\n%s", localize(interaction, "Failed (%d)", len(failed)), strings.Join(failed, "\n"))
+	}
+	return truncateMessage(sb.String())
+}
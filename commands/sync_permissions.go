@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+const (
+	syncPermissionSubjectRole = "role"
+	syncPermissionSubjectUser = "user"
+)
+
+// Members need Manage Channels to see the sync management commands by default.
+// Guild admins can change this per command in the guild's integration settings.
+var manageSyncsPermission int64 = discordgo.PermissionManageChannels
+
+// Managing who may manage syncs is limited to members who can manage the guild.
+var manageSyncPermissionsPermission int64 = discordgo.PermissionManageServer
+
+var commandConfigSyncPermissions = CommandConfig{
+	info: &discordgo.ApplicationCommand{
+		Name:                     "sync-permissions",
+		Description:              "Manage which roles and users may manage syncs",
+		DefaultMemberPermissions: &manageSyncPermissionsPermission,
+		DMPermission:             &allowInDMs,
+	},
+	// Allowlisted members must not be able to grant themselves more access,
+	// so every subcommand also checks for Manage Server.
+	subcommands: []SubcommandConfig{
+		{
+			info: &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "allow",
+				Description: "Allow a role or user to manage syncs",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionMentionable,
+						Name:        "subject",
+						Description: "Role or user",
+						Required:    true,
+					},
+				},
+			},
+			routes: commandRoutes{
+				handler: requireManageServer(handleAllowSyncPermissionCommand),
+			},
+		},
+		{
+			info: &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "revoke",
+				Description: "Remove a role or user from the allowlist",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionMentionable,
+						Name:        "subject",
+						Description: "Role or user",
+						Required:    true,
+					},
+				},
+			},
+			routes: commandRoutes{
+				handler: requireManageServer(handleRevokeSyncPermissionCommand),
+			},
+		},
+		{
+			info: &discordgo.ApplicationCommandOption{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "List the roles and users allowed to manage syncs",
+			},
+			routes: commandRoutes{
+				handler: requireManageServer(handleListSyncPermissionsCommand),
+			},
+		},
+	},
+}
+
+func handleAllowSyncPermissionCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	subjectType, subjectId, ok := syncPermissionSubjectOption(session, interaction, logger)
+	if !ok {
+		return
+	}
+
+	err := appCtx.DB.AddSyncPermission(context.Background(), db.AddSyncPermissionParams{
+		GuildID:     interaction.GuildID,
+		SubjectType: subjectType,
+		SubjectID:   subjectId,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	mention := formatSyncPermissionSubject(subjectType, subjectId)
+	sendEphemeralResponse(session, interaction, localize(interaction, "%s may now manage syncs.", mention))
+}
+
+func handleRevokeSyncPermissionCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	subjectType, subjectId, ok := syncPermissionSubjectOption(session, interaction, logger)
+	if !ok {
+		return
+	}
+
+	removed, err := appCtx.DB.RemoveSyncPermission(context.Background(), db.RemoveSyncPermissionParams{
+		GuildID:     interaction.GuildID,
+		SubjectType: subjectType,
+		SubjectID:   subjectId,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	mention := formatSyncPermissionSubject(subjectType, subjectId)
+	if removed == 0 {
+		sendEphemeralResponse(session, interaction, localize(interaction, "%s is not on the allowlist.", mention))
+		return
+	}
+	sendEphemeralResponse(session, interaction, localize(interaction, "Removed %s from the allowlist.", mention))
+}
+
+func handleListSyncPermissionsCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	permissions, err := appCtx.DB.GetGuildSyncPermissions(context.Background(), interaction.GuildID)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if len(permissions) == 0 {
+		sendEphemeralResponse(session, interaction, localize(interaction, "No allowlist is set. Anyone who can use the sync commands may manage syncs."))
+		return
+	}
+
+	mentions := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		mentions = append(mentions, formatSyncPermissionSubject(permission.SubjectType, permission.DiscordSubjectSnowflake))
+	}
+	msg := localize(interaction, "Allowed to manage syncs, along with administrators:") + "\n" + strings.Join(mentions, "\n")
+	sendEphemeralResponse(session, interaction, truncateMessage(msg))
+}
+
+// Resolves the subject option of allow and revoke, responding with an error if it can't be resolved.
+func syncPermissionSubjectOption(session *discordgo.Session, interaction *discordgo.Interaction, logger zerolog.Logger) (string, string, bool) {
+	option := commandOptions(interaction)[0]
+	subjectType, subjectId := resolveSyncPermissionSubject(interaction, option)
+	if subjectType == "" {
+		logger.Error().Interface("option", option).Msg("Failed to resolve mentionable option.")
+		sendErrorResponse(session, interaction)
+		return "", "", false
+	}
+	return subjectType, subjectId, true
+}
+
+// Checks that the member who triggered the interaction may manage syncs, responding with an explanation if not.
+// When a guild has no allowlist, the commands' default member permissions are the only restriction.
+// Administrators are always allowed so a guild can't lock itself out.
+func ensureSyncPermission(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) bool {
+	allowed, err := hasSyncPermission(context.Background(), *appCtx, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return false
+	}
+	if !allowed {
+		logger.Info().Msg("Member is not allowed to manage syncs.")
+		sendEphemeralResponse(session, interaction, localize(interaction, "You are not allowed to manage syncs in this guild."))
+		return false
+	}
+	return true
+}
+
+func hasSyncPermission(ctx context.Context, appCtx config.AppCtx, interaction *discordgo.Interaction) (bool, error) {
+	member := interaction.Member
+	if member == nil {
+		return false, nil
+	}
+	if member.Permissions&discordgo.PermissionAdministrator != 0 {
+		return true, nil
+	}
+
+	permissions, err := appCtx.DB.GetGuildSyncPermissions(ctx, interaction.GuildID)
+	if err != nil {
+		return false, err
+	}
+	if len(permissions) == 0 {
+		return true, nil
+	}
+
+	for _, permission := range permissions {
+		switch permission.SubjectType {
+		case syncPermissionSubjectUser:
+			if member.User != nil && member.User.ID == permission.DiscordSubjectSnowflake {
+				return true, nil
+			}
+		case syncPermissionSubjectRole:
+			// The @everyone role shares the guild's ID and is not listed in the member's roles.
+			if slices.Contains(member.Roles, permission.DiscordSubjectSnowflake) || permission.DiscordSubjectSnowflake == interaction.GuildID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Gets whether a mentionable option is a role or a user, along with its snowflake.
+// Returns an empty subject type if the option can't be resolved.
+func resolveSyncPermissionSubject(interaction *discordgo.Interaction, opt *discordgo.ApplicationCommandInteractionDataOption) (string, string) {
+	subjectId, _ := opt.Value.(string)
+	resolved := interaction.ApplicationCommandData().Resolved
+	if resolved == nil {
+		return "", ""
+	}
+	if _, ok := resolved.Roles[subjectId]; ok {
+		return syncPermissionSubjectRole, subjectId
+	}
+	if _, ok := resolved.Users[subjectId]; ok {
+		return syncPermissionSubjectUser, subjectId
+	}
+	return "", ""
+}
+
+func formatSyncPermissionSubject(subjectType string, subjectId string) string {
+	if subjectType == syncPermissionSubjectRole {
+		return fmt.Sprintf("<@&%s>", subjectId)
+	}
+	return fmt.Sprintf("<@%s>", subjectId)
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+// Maximum number of diff lines shown for a single chunk in a sync preview.
+const previewMaxDiffLines = 8
+
+// Describes what syncing a file would do to the channel's messages without changing anything.
+// Only reads from the database and the file URL. Discord is not contacted.
+func previewFileSync(ctx context.Context, appCtx config.AppCtx, interaction *discordgo.Interaction, fileToSync db.FilesToSync) (string, error) {
+	logger := zerolog.Ctx(ctx)
+	channelId := fileToSync.DiscordChannelSnowflake
+	fileUrl := fileToSync.FileToSyncUri
+	prevFileContents := fileToSync.FileContents
+
+	fileContents, _, err := fetchFileContents(ctx, fileUrl)
+	if err != nil {
+		return localize(interaction, "Could not fetch %s: %s", fileUrl, err), nil
+	}
+
+	if fileContents == prevFileContents {
+		return localize(interaction, "<#%s> is already up to date with %s.", channelId, fileUrl), nil
+	}
+
+	contentChunks, contentHashes, attached := fileMessageContents(fileToSync, fileContents)
+	prevContentChunks, _, _ := fileMessageContents(fileToSync, prevFileContents)
+
+	existingMessageChunkRows, err := appCtx.DB.GetFileContentChunks(context.Background(), fileToSync.ID)
+	if err != nil {
+		return "", err
+	}
+	msgIds, msgHashes, excessMsgIds := planChunkMessages(contentHashes, existingMessageChunkRows, fileToSync.DiscordThreadSnowflake != "")
+
+	// Diff each message against the chunk it holds now, which may have a different number than the chunk replacing it.
+	prevChunks := make(map[string]string, len(existingMessageChunkRows))
+	for _, chunkRow := range existingMessageChunkRows {
+		if chunkIndex := int(chunkRow.ChunkNumber) - 1; chunkIndex >= 0 && chunkIndex < len(prevContentChunks) {
+			prevChunks[chunkRow.DiscordMessageID] = prevContentChunks[chunkIndex]
+		}
+	}
+
+	var edited, created, unchanged int
+	changes := make([]string, 0)
+	// An attachment holds the whole file, which is too large to diff, so only its change in size is shown.
+	previewDiff := func(old string, new string) []string {
+		if attached {
+			return nil
+		}
+		return lineDiff(old, new)
+	}
+	for i, chunk := range contentChunks {
+		if msgIds[i] == "" {
+			created++
+			changes = append(changes, formatPreviewChange(localize(interaction, "Chunk %d: new message", i+1), previewDiff("", chunk)))
+			continue
+		}
+
+		// The sync skips messages whose stored hash matches, the same as here.
+		if msgHashes[i] == contentHashes[i] {
+			unchanged++
+			continue
+		}
+		edited++
+		changes = append(changes, formatPreviewChange(localize(interaction, "Chunk %d: edit message %s", i+1, msgIds[i]), previewDiff(prevChunks[msgIds[i]], chunk)))
+	}
+	for _, msgId := range excessMsgIds {
+		changes = append(changes, formatPreviewChange(localize(interaction, "Delete message %s", msgId), nil))
+	}
+
+	logger.Info().
+		Int("chunks_edited", edited).
+		Int("chunks_created", created).
+		Int("chunks_deleted", len(excessMsgIds)).
+		Int("chunks_unchanged", unchanged).
+		Msg("Previewed sync.")
+
+	var sb strings.Builder
+	sb.WriteString(localize(interaction, "Preview of syncing %s to <#%s>", fileUrl, channelId) + "\n")
+	sb.WriteString(localize(interaction, "%d edited, %d created, %d deleted, %d unchanged.", edited, created, len(excessMsgIds), unchanged))
+	if attached {
+		sb.WriteString("\n" + localize(interaction, "The file would be posted as an attachment."))
+		sb.WriteString("\n" + localize(interaction, "It would go from %d lines and %d bytes to %d lines and %d bytes.", countLines(prevFileContents), len(prevFileContents), countLines(fileContents), len(fileContents)))
+	}
+	for i, change := range changes {
+		more := "\n" + localize(interaction, "...and %d more changes.", len(changes)-i)
+		if sb.Len()+len(change)+len(more) > maxMessageLength {
+			sb.WriteString(more)
+			break
+		}
+		sb.WriteString(change)
+	}
+	return sb.String(), nil
+}
+
+func formatPreviewChange(title string, diff []string) string {
+	if len(diff) == 0 {
+		return "\n**" + title + "**"
+	}
+
+	lines := diff
+	if len(lines) > previewMaxDiffLines {
+		lines = append(lines[:previewMaxDiffLines:previewMaxDiffLines], fmt.Sprintf("... %d more lines", len(diff)-previewMaxDiffLines))
+	}
+	// Keep the chunk's own backticks from closing the code block early.
+	body := strings.ReplaceAll(strings.Join(lines, "\n"), "```", "`\u200b``")
+	return "\n**" + title + "**\n```diff\n" + body + "\n```"
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+var commandConfigShowSyncSource = CommandConfig{
+	info: &discordgo.ApplicationCommand{
+		Type:         discordgo.MessageApplicationCommand,
+		Name:         "Show sync source",
+		DMPermission: &allowInDMs,
+	},
+	routes: commandRoutes{
+		handler: handleShowSyncSourceCommand,
+	},
+}
+
+func handleShowSyncSourceCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Synced messages are public, so anyone who can see one may look up where it came from.
+	messageId := interaction.ApplicationCommandData().TargetID
+	source, err := appCtx.DB.GetChunkMessageSource(context.Background(), db.GetChunkMessageSourceParams{
+		MessageID: messageId,
+		GuildID:   interaction.GuildID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			sendEphemeralResponse(session, interaction, localize(interaction, "This message is not part of a sync."))
+			return
+		}
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+
+	err = session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{buildSyncSourceEmbed(interaction, source)},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+func buildSyncSourceEmbed(interaction *discordgo.Interaction, source db.GetChunkMessageSourceRow) *discordgo.MessageEmbed {
+	githubRepo := localize(interaction, "None")
+	if source.GithubRepoUrl.Valid {
+		githubRepo = source.GithubRepoUrl.String
+	}
+
+	// Line numbers are worked out from the last synced contents, which the message was posted from.
+	lines := localize(interaction, "Unknown")
+	if startLine, endLine, ok := chunkLineRange(source.FileContents, source.AttachmentMode, int(source.ChunkNumber)); ok {
+		lines = fmt.Sprintf("%d-%d", startLine, endLine)
+		if blobUrl, ok := githubBlobUrl(source.Url); ok {
+			lines = fmt.Sprintf("[%s](%s#L%d-L%d)", lines, blobUrl, startLine, endLine)
+		}
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       localize(interaction, "Sync Source"),
+		Description: source.Url,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: localize(interaction, "Channel"), Value: fmt.Sprintf("<#%s>", source.ChannelID), Inline: true},
+			{Name: localize(interaction, "Chunk"), Value: localize(interaction, "%d of %d", source.ChunkNumber, source.ChunkCount), Inline: true},
+			{Name: localize(interaction, "Lines"), Value: lines, Inline: true},
+			{Name: localize(interaction, "Last synced"), Value: formatDiscordTimestamp(interaction, source.LastSyncedAt), Inline: true},
+			{Name: localize(interaction, "GitHub repo"), Value: githubRepo},
+		},
+	}
+}
+
+// Returns the first and last line of contents covered by the given 1-based chunk.
+// A file posted as an attachment is covered whole by its only message.
+func chunkLineRange(contents string, attachmentMode string, chunkNumber int) (int, int, bool) {
+	if usesAttachment(attachmentMode, contents) {
+		return 1, countLines(contents), chunkNumber == 1
+	}
+	chunks := chunkMarkdown(contents, maxChunkLength)
+	if chunkNumber < 1 || chunkNumber > len(chunks) {
+		return 0, 0, false
+	}
+	chunk := chunks[chunkNumber-1]
+	return chunk.startLine, chunk.endLine, true
+}
+
+// Converts a raw GitHub file URL into the URL of the file's page on GitHub.
+// Plain view is requested so line anchors work for rendered files like markdown.
+func githubBlobUrl(rawUrl string) (string, bool) {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", false
+	}
+
+	// raw.githubusercontent.com/<owner>/<repo>/<ref>/<path> or github.com/<owner>/<repo>/raw/<ref>/<path>
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	switch parsed.Host {
+	case "raw.githubusercontent.com":
+	case "github.com", "www.github.com":
+		if len(segments) < 3 || segments[2] != "raw" {
+			return "", false
+		}
+		segments = append(segments[:2], segments[3:]...)
+	default:
+		return "", false
+	}
+
+	// Fully qualified refs like refs/heads/main span several segments.
+	if len(segments) > 4 && segments[2] == "refs" && (segments[3] == "heads" || segments[3] == "tags") {
+		segments = append(segments[:2], segments[4:]...)
+	}
+	if len(segments) < 4 {
+		return "", false
+	}
+
+	owner, repo, ref, path := segments[0], segments[1], segments[2], strings.Join(segments[3:], "/")
+	return fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s?plain=1", owner, repo, ref, path), true
+}
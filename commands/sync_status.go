@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+	"github.com/rs/zerolog"
+)
+
+var subcommandConfigSyncStatus = SubcommandConfig{
+	info: &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Name:        "status",
+		Description: "Show the health of a channel's sync",
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(syncChannelTypes),
+			channelIdOption(true),
+			fileOption(),
+		},
+	},
+	routes: commandRoutes{
+		handler:      requireSyncPermission(handleSyncStatusCommand),
+		autocomplete: requireSyncPermission(autocompleteSyncedChannels),
+	},
+}
+
+func handleSyncStatusCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	channelId, ok := channelIdFromOptions(optionMap)
+	if !ok {
+		sendEphemeralResponse(session, interaction, localize(interaction, "Either channel or channel-id must be given."))
+		return
+	}
+
+	fileToSync, msg, err := resolveFileSyncOption(context.Background(), *appCtx, interaction, optionMap, channelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if msg != "" {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+
+	err = session.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{buildSyncStatusEmbed(interaction, fileToSync)},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+// Maximum length of a discord embed field's value. Errors from discord include the whole response body,
+// so the last error can be longer than this.
+const maxEmbedFieldLength = 1024
+
+func buildSyncStatusEmbed(interaction *discordgo.Interaction, fileToSync db.FilesToSync) *discordgo.MessageEmbed {
+	status := localize(interaction, "Healthy")
+	color := 0x57F287
+	if fileToSync.Paused {
+		status = localize(interaction, "Paused")
+		color = 0x95A5A6
+	} else if !fileToSync.LastAttemptAt.Valid {
+		status = localize(interaction, "Never synced")
+		color = 0x95A5A6
+	} else if fileToSync.LastError != "" {
+		status = localize(interaction, "Failing")
+		color = 0xED4245
+	}
+
+	httpStatus := localize(interaction, "None")
+	if fileToSync.LastHttpStatus.Valid {
+		httpStatus = strconv.Itoa(int(fileToSync.LastHttpStatus.Int32))
+	}
+
+	lastError := localize(interaction, "None")
+	if fileToSync.LastError != "" {
+		lastError = truncateText(fileToSync.LastError, maxEmbedFieldLength)
+	}
+
+	contentHash := localize(interaction, "None")
+	if fileToSync.ContentHash != "" {
+		contentHash = "`" + fileToSync.ContentHash + "`"
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       localize(interaction, "Sync Status: %s", status),
+		Description: fmt.Sprintf("<#%s>\n%s", fileToSync.DiscordChannelSnowflake, fileToSync.FileToSyncUri),
+		Color:       color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: localize(interaction, "Last attempt"), Value: formatDiscordTimestamp(interaction, fileToSync.LastAttemptAt), Inline: true},
+			{Name: localize(interaction, "Last success"), Value: formatDiscordTimestamp(interaction, fileToSync.LastSyncedAt), Inline: true},
+			{Name: localize(interaction, "HTTP status"), Value: httpStatus, Inline: true},
+			{Name: localize(interaction, "Last error"), Value: lastError},
+			{Name: localize(interaction, "Content hash (SHA-256)"), Value: contentHash},
+		},
+	}
+}
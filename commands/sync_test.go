@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/michaeldoylecs/discord-sync-bot/db"
+)
+
+// Builds the stored chunks of messages holding the given content hashes, with the message IDs m1, m2, and so on.
+func existingChunks(hashes ...string) []db.GetFileContentChunksRow {
+	chunks := make([]db.GetFileContentChunksRow, len(hashes))
+	for i, hash := range hashes {
+		chunks[i] = db.GetFileContentChunksRow{
+			ChunkNumber:      int32(i + 1),
+			DiscordMessageID: fmt.Sprintf("m%d", i+1),
+			ContentHash:      hash,
+		}
+	}
+	return chunks
+}
+
+func TestPlanChunkMessages(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    []db.GetFileContentChunksRow
+		chunkHashes []string
+		keepFirst   bool
+		// Message holding each chunk, or "" for a new message.
+		wantMsgIds    []string
+		wantExcessIds []string
+		// Messages edited, sent, or deleted.
+		wantTouched int
+	}{
+		{
+			name:          "unchanged",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"a", "b", "c"},
+			wantMsgIds:    []string{"m1", "m2", "m3"},
+			wantExcessIds: []string{},
+			wantTouched:   0,
+		},
+		{
+			name:          "edited chunk",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"a", "x", "c"},
+			wantMsgIds:    []string{"m1", "m2", "m3"},
+			wantExcessIds: []string{},
+			wantTouched:   1,
+		},
+		{
+			name:          "inserted chunk shifts the chunks after it down",
+			existing:      existingChunks("a", "b", "c", "d"),
+			chunkHashes:   []string{"a", "b", "x", "c", "d"},
+			wantMsgIds:    []string{"m1", "m2", "m3", "m4", ""},
+			wantExcessIds: []string{},
+			wantTouched:   3,
+		},
+		{
+			name:          "appended chunk",
+			existing:      existingChunks("a", "b"),
+			chunkHashes:   []string{"a", "b", "c"},
+			wantMsgIds:    []string{"m1", "m2", ""},
+			wantExcessIds: []string{},
+			wantTouched:   1,
+		},
+		{
+			name:          "removed chunk deletes its message",
+			existing:      existingChunks("a", "b", "c", "d"),
+			chunkHashes:   []string{"a", "c", "d"},
+			wantMsgIds:    []string{"m1", "m3", "m4"},
+			wantExcessIds: []string{"m2"},
+			wantTouched:   1,
+		},
+		{
+			name:          "reordered chunks",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"c", "a", "b"},
+			wantMsgIds:    []string{"m1", "m2", "m3"},
+			wantExcessIds: []string{},
+			wantTouched:   3,
+		},
+		{
+			name:          "swapped chunks",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"b", "a", "c"},
+			wantMsgIds:    []string{"m1", "m2", "m3"},
+			wantExcessIds: []string{},
+			wantTouched:   2,
+		},
+		{
+			name:          "removed first chunk",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"b", "c"},
+			wantMsgIds:    []string{"m2", "m3"},
+			wantExcessIds: []string{"m1"},
+			wantTouched:   1,
+		},
+		{
+			name:          "removed first chunk keeps the first message",
+			existing:      existingChunks("a", "b", "c"),
+			chunkHashes:   []string{"b", "c"},
+			keepFirst:     true,
+			wantMsgIds:    []string{"m1", "m3"},
+			wantExcessIds: []string{"m2"},
+			wantTouched:   2,
+		},
+		{
+			name:          "no chunks",
+			existing:      existingChunks("a", "b"),
+			chunkHashes:   []string{},
+			wantMsgIds:    []string{},
+			wantExcessIds: []string{"m1", "m2"},
+			wantTouched:   2,
+		},
+		{
+			name:          "no chunks keeps the first message",
+			existing:      existingChunks("a", "b"),
+			chunkHashes:   []string{},
+			keepFirst:     true,
+			wantMsgIds:    []string{},
+			wantExcessIds: []string{"m2"},
+			wantTouched:   1,
+		},
+		{
+			name:          "no existing messages",
+			existing:      existingChunks(),
+			chunkHashes:   []string{"a", "b"},
+			wantMsgIds:    []string{"", ""},
+			wantExcessIds: []string{},
+			wantTouched:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgIds, msgHashes, excessMsgIds := planChunkMessages(tt.chunkHashes, tt.existing, tt.keepFirst)
+			if !reflect.DeepEqual(msgIds, tt.wantMsgIds) {
+				t.Errorf("msgIds = %q, want %q", msgIds, tt.wantMsgIds)
+			}
+			if !reflect.DeepEqual(excessMsgIds, tt.wantExcessIds) {
+				t.Errorf("excessMsgIds = %q, want %q", excessMsgIds, tt.wantExcessIds)
+			}
+
+			// Each kept message is returned with the hash of what it holds now, which decides whether it is edited.
+			touched := len(excessMsgIds)
+			for i, msgId := range msgIds {
+				wantHash := ""
+				for _, chunk := range tt.existing {
+					if chunk.DiscordMessageID == msgId {
+						wantHash = chunk.ContentHash
+					}
+				}
+				if msgHashes[i] != wantHash {
+					t.Errorf("msgHashes[%d] = %q, want %q", i, msgHashes[i], wantHash)
+				}
+				if msgHashes[i] != tt.chunkHashes[i] {
+					touched++
+				}
+			}
+			if touched != tt.wantTouched {
+				t.Errorf("touched %d messages, want %d", touched, tt.wantTouched)
+			}
+		})
+	}
+}
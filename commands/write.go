@@ -1,27 +1,213 @@
 package commands
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/bwmarrin/discordgo"
 	"github.com/michaeldoylecs/discord-sync-bot/config"
+	"github.com/rs/zerolog"
 )
 
+// Maximum number of messages written to a single Markdown file.
+const writeMarkdownMaxMessages = 1000
+
+// Channel types that hold messages directly.
+var writeMarkdownChannelTypes = []discordgo.ChannelType{
+	discordgo.ChannelTypeGuildText,
+	discordgo.ChannelTypeGuildNews,
+}
+
 var commandConfigWrite = CommandConfig{
 	info: &discordgo.ApplicationCommand{
-		Name:        "write-markdown",
-		Description: "Writes messages given link to a Markdown file",
+		Name:                     "write-markdown",
+		Description:              "Writes a channel's messages to a Markdown file",
+		DefaultMemberPermissions: &manageSyncsPermission,
+		DMPermission:             &allowInDMs,
+		Options: []*discordgo.ApplicationCommandOption{
+			channelOption(writeMarkdownChannelTypes),
+			channelIdOption(false),
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "start-message-id",
+				Description: "ID of the first message to write. Defaults to the start of the channel.",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "end-message-id",
+				Description: "ID of the last message to write. Defaults to the latest message.",
+				Required:    false,
+			},
+		},
 	},
-	handler: func(discordSession *discordgo.Session, _ *config.AppCtx) {
-		discordSession.AddHandler(func(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
-			if interaction.ApplicationCommandData().Name != "write-markdown" {
-				return
+	routes: commandRoutes{
+		handler: requireSyncPermission(handleWriteMarkdownCommand),
+	},
+}
+
+func handleWriteMarkdownCommand(session *discordgo.Session, interaction *discordgo.Interaction, appCtx *config.AppCtx, logger zerolog.Logger) {
+	// Build options map
+	options := commandOptions(interaction)
+	optionMap := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	startMessageId := ""
+	if opt, ok := optionMap["start-message-id"]; ok {
+		startMessageId = opt.StringValue()
+	}
+	endMessageId := ""
+	if opt, ok := optionMap["end-message-id"]; ok {
+		endMessageId = opt.StringValue()
+	}
+
+	for _, messageId := range []string{startMessageId, endMessageId} {
+		if _, err := parseSnowflake(messageId); messageId != "" && err != nil {
+			msg := localize(interaction, "'%s' is not a valid message ID.", messageId)
+			sendEphemeralResponse(session, interaction, msg)
+			return
+		}
+	}
+
+	channel, msg, err := resolveChannelOption(session, interaction, optionMap, writeMarkdownChannelTypes)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	if channel == nil {
+		sendEphemeralResponse(session, interaction, msg)
+		return
+	}
+	channelId := channel.ID
+
+	// The bot reads the history with its own permissions, so members may only write channels they can read themselves.
+	permissions, err := session.UserChannelPermissions(interaction.Member.User.ID, channelId)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to get the member's channel permissions.")
+		sendErrorResponse(session, interaction)
+		return
+	}
+	required := int64(discordgo.PermissionViewChannel | discordgo.PermissionReadMessageHistory)
+	if permissions&required != required {
+		logger.Info().Str("channel_id", channelId).Msg("Member can not read the channel's history.")
+		sendEphemeralResponse(session, interaction, localize(interaction, "You need to be able to view <#%s> and read its message history to write it to a file.", channelId))
+		return
+	}
+
+	// Reading a long history can take longer than the interaction deadline.
+	err = sendDeferredEphemeralResponse(session, interaction)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		return
+	}
+
+	messages, err := getChannelMessageRange(session, channelId, startMessageId, endMessageId)
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+		editErrorResponse(session, interaction)
+		return
+	}
+
+	if len(messages) == 0 {
+		editResponse(session, interaction, localize(interaction, "No messages found in the given range."))
+		return
+	}
+
+	markdown := messagesToMarkdown(messages)
+	msg = localize(interaction, "Wrote %d messages from <#%s>.", len(messages), channelId)
+	if len(messages) == writeMarkdownMaxMessages {
+		msg += " " + localize(interaction, "Stopped at the limit of %d messages.", writeMarkdownMaxMessages)
+	}
+	_, err = session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+		Content: &msg,
+		Files: []*discordgo.File{
+			{
+				Name:        channel.Name + ".md",
+				ContentType: "text/markdown",
+				Reader:      strings.NewReader(markdown),
+			},
+		},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("")
+	}
+}
+
+// Gets the channel's messages between startMessageId and endMessageId inclusive, oldest first.
+// An empty start or end leaves that side of the range open.
+func getChannelMessageRange(session *discordgo.Session, channelId string, startMessageId string, endMessageId string) ([]*discordgo.Message, error) {
+	var start uint64
+	if startMessageId != "" {
+		start, _ = parseSnowflake(startMessageId)
+	}
+
+	messages := make([]*discordgo.Message, 0)
+	beforeId := ""
+	if endMessageId != "" {
+		endMessage, err := session.ChannelMessage(channelId, endMessageId)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, endMessage)
+		beforeId = endMessageId
+	}
+
+	// Messages are returned newest first, so page backwards until the start of the range.
+	for len(messages) < writeMarkdownMaxMessages {
+		batch, err := session.ChannelMessages(channelId, 100, beforeId, "", "")
+		if err != nil {
+			return nil, err
+		}
+
+		reachedStart := false
+		for _, message := range batch {
+			id, _ := parseSnowflake(message.ID)
+			if id < start || len(messages) == writeMarkdownMaxMessages {
+				reachedStart = true
+				break
 			}
+			messages = append(messages, message)
+		}
 
-			session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "Test message!",
-				},
-			})
-		})
-	},
+		if reachedStart || len(batch) < 100 {
+			break
+		}
+		beforeId = batch[len(batch)-1].ID
+	}
+
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// Joins the messages into a single Markdown document, one paragraph per message.
+// Attachments are written as links after the message's content.
+func messagesToMarkdown(messages []*discordgo.Message) string {
+	paragraphs := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if message.Type != discordgo.MessageTypeDefault && message.Type != discordgo.MessageTypeReply {
+			continue
+		}
+
+		lines := make([]string, 0, 1+len(message.Attachments))
+		if content := strings.TrimSpace(message.Content); content != "" {
+			lines = append(lines, content)
+		}
+		for _, attachment := range message.Attachments {
+			lines = append(lines, fmt.Sprintf("[%s](%s)", attachment.Filename, attachment.URL))
+		}
+
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
+
+func parseSnowflake(snowflake string) (uint64, error) {
+	return strconv.ParseUint(snowflake, 10, 64)
 }
@@ -2,10 +2,12 @@ package config
 
 import (
 	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/michaeldoylecs/discord-sync-bot/db"
 )
 
 type AppCtx struct {
 	DB             *db.Queries
+	DBPool         *pgxpool.Pool
 	DiscordSession *discordgo.Session
 }
@@ -0,0 +1,9 @@
+-- migrate:up
+ALTER TABLE files_to_sync
+  ADD COLUMN last_synced_at timestamptz
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS last_synced_at
+;
@@ -0,0 +1,20 @@
+-- migrate:up
+ALTER TABLE files_to_sync
+  ADD COLUMN last_attempt_at timestamptz
+  ,ADD COLUMN last_error text NOT NULL DEFAULT ''
+  ,ADD COLUMN last_http_status int
+  ,ADD COLUMN content_hash varchar(64) NOT NULL DEFAULT ''
+;
+
+UPDATE files_to_sync
+SET content_hash = encode(sha256(convert_to(file_contents, 'UTF8')), 'hex')
+WHERE file_contents <> ''
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS last_attempt_at
+  ,DROP COLUMN IF EXISTS last_error
+  ,DROP COLUMN IF EXISTS last_http_status
+  ,DROP COLUMN IF EXISTS content_hash
+;
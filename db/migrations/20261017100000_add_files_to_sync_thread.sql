@@ -0,0 +1,10 @@
+-- migrate:up
+-- Forum channels can't hold messages directly, so a forum post (thread) is created to hold the synced messages.
+ALTER TABLE files_to_sync
+  ADD COLUMN discord_thread_snowflake varchar(20) NOT NULL DEFAULT ''
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS discord_thread_snowflake
+;
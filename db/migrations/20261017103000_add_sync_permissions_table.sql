@@ -0,0 +1,12 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS sync_permissions (
+  id bigserial PRIMARY KEY
+  ,discord_guild_snowflake varchar(20) NOT NULL
+  ,subject_type varchar(8) NOT NULL CHECK (subject_type IN ('role', 'user'))
+  ,discord_subject_snowflake varchar(20) NOT NULL
+  ,UNIQUE (discord_guild_snowflake, subject_type, discord_subject_snowflake)
+)
+;
+
+-- migrate:down
+DROP TABLE IF EXISTS sync_permissions;
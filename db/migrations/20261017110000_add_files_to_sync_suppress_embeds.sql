@@ -0,0 +1,10 @@
+-- migrate:up
+-- Hides link previews on a sync's chunk messages.
+ALTER TABLE files_to_sync
+  ADD COLUMN suppress_embeds boolean NOT NULL DEFAULT false
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS suppress_embeds
+;
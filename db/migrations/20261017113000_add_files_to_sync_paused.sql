@@ -0,0 +1,10 @@
+-- migrate:up
+-- Paused syncs are skipped until resumed, keeping their messages and settings.
+ALTER TABLE files_to_sync
+  ADD COLUMN paused boolean NOT NULL DEFAULT false
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS paused
+;
@@ -0,0 +1,10 @@
+-- migrate:up
+-- Pins a sync's chunk messages as they are posted.
+ALTER TABLE files_to_sync
+  ADD COLUMN pin_messages boolean NOT NULL DEFAULT false
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS pin_messages
+;
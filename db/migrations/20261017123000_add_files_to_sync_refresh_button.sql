@@ -0,0 +1,10 @@
+-- migrate:up
+-- Adds a button to a sync's last chunk message that lets anyone refresh the sync.
+ALTER TABLE files_to_sync
+  ADD COLUMN refresh_button boolean NOT NULL DEFAULT false
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS refresh_button
+;
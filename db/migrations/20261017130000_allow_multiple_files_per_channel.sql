@@ -0,0 +1,17 @@
+-- migrate:up
+-- A channel can sync several files. Their messages are kept in position order,
+-- and each file's first message can be headed to separate it from the file before.
+ALTER TABLE files_to_sync
+  DROP CONSTRAINT files_to_sync_unique_channel
+  ,ADD COLUMN position integer NOT NULL DEFAULT 0
+  ,ADD COLUMN heading varchar(48) NOT NULL DEFAULT ''
+  ,ADD CONSTRAINT files_to_sync_unique_channel_file UNIQUE (discord_guild_snowflake, discord_channel_snowflake, file_to_sync_uri)
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP CONSTRAINT IF EXISTS files_to_sync_unique_channel_file
+  ,DROP COLUMN IF EXISTS heading
+  ,DROP COLUMN IF EXISTS position
+  ,ADD CONSTRAINT files_to_sync_unique_channel UNIQUE (discord_guild_snowflake, discord_channel_snowflake)
+;
@@ -0,0 +1,11 @@
+-- migrate:up
+-- Stores the hash of each chunk message's content, so unchanged messages are not edited again.
+-- Existing messages are left without a hash and are edited once by their next sync.
+ALTER TABLE file_chunk_messages
+  ADD COLUMN content_hash varchar(64) NOT NULL DEFAULT ''
+;
+
+-- migrate:down
+ALTER TABLE file_chunk_messages
+  DROP COLUMN IF EXISTS content_hash
+;
@@ -0,0 +1,11 @@
+-- migrate:up
+-- Large files can be posted as an uploaded attachment under a short summary message instead of as many messages.
+-- 'auto' switches to an attachment once the file is too large, and the other modes always use one or the other.
+ALTER TABLE files_to_sync
+  ADD COLUMN attachment_mode varchar(10) NOT NULL DEFAULT 'auto' CHECK (attachment_mode IN ('auto', 'messages', 'attachment'))
+;
+
+-- migrate:down
+ALTER TABLE files_to_sync
+  DROP COLUMN IF EXISTS attachment_mode
+;
@@ -4,11 +4,16 @@
 
 package db
 
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
 type FileChunkMessage struct {
 	ID               int64
 	FilesToSyncFk    int64
 	ChunkNumber      int32
 	DiscordMessageID string
+	ContentHash      string
 }
 
 type FilesToSync struct {
@@ -17,6 +22,19 @@ type FilesToSync struct {
 	DiscordChannelSnowflake string
 	ID                      int64
 	FileContents            string
+	LastSyncedAt            pgtype.Timestamptz
+	LastAttemptAt           pgtype.Timestamptz
+	LastError               string
+	LastHttpStatus          pgtype.Int4
+	ContentHash             string
+	DiscordThreadSnowflake  string
+	SuppressEmbeds          bool
+	Paused                  bool
+	PinMessages             bool
+	RefreshButton           bool
+	Position                int32
+	Heading                 string
+	AttachmentMode          string
 }
 
 type GithubRepoFile struct {
@@ -28,3 +46,10 @@ type GithubRepoFile struct {
 type SchemaMigration struct {
 	Version string
 }
+
+type SyncPermission struct {
+	ID                      int64
+	DiscordGuildSnowflake   string
+	SubjectType             string
+	DiscordSubjectSnowflake string
+}
@@ -1,57 +1,212 @@
 -- name: AddChannelSync :one
-INSERT INTO files_to_sync (file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake)
-VALUES ($1, $2, $3)
-ON CONFLICT (discord_guild_snowflake, discord_channel_snowflake)
-DO UPDATE SET file_to_sync_uri = $1
+INSERT INTO files_to_sync (file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, suppress_embeds, pin_messages, refresh_button, heading, attachment_mode, position)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, (
+  SELECT COALESCE(MAX(position), 0) + 1
+  FROM files_to_sync
+  WHERE discord_guild_snowflake = $2
+    AND discord_channel_snowflake = $3
+))
+ON CONFLICT (discord_guild_snowflake, discord_channel_snowflake, file_to_sync_uri)
+DO UPDATE SET suppress_embeds = $4
+  ,pin_messages = $5
+  ,refresh_button = $6
+  ,heading = $7
+  ,attachment_mode = $8
 RETURNING *
 ;
 
--- name: GetChannelSync :one
-SELECT *
-FROM files_to_sync
-WHERE file_to_sync_uri = @file_uri
-;
-
 -- name: SetFileSyncContents :exec
 UPDATE files_to_sync
 SET file_contents = @file_contents
-WHERE discord_channel_snowflake = @channel_id
+  ,content_hash = @content_hash
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncAttempt :exec
+UPDATE files_to_sync
+SET last_attempt_at = now()
+  ,last_error = @last_error
+  ,last_http_status = @last_http_status
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncedAt :exec
+UPDATE files_to_sync
+SET last_synced_at = now()
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncUri :exec
+UPDATE files_to_sync
+SET file_to_sync_uri = @file_uri
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncPinMessages :exec
+UPDATE files_to_sync
+SET pin_messages = @pin_messages
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncRefreshButton :exec
+UPDATE files_to_sync
+SET refresh_button = @refresh_button
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncSuppressEmbeds :exec
+UPDATE files_to_sync
+SET suppress_embeds = @suppress_embeds
+WHERE id = @file_to_sync_id
+;
+
+-- name: MoveChannelSync :exec
+UPDATE files_to_sync
+SET discord_channel_snowflake = @new_channel_id
+  ,position = (
+    SELECT COALESCE(MAX(position), 0) + 1
+    FROM files_to_sync
+    WHERE discord_channel_snowflake = @new_channel_id
+  )
+  ,discord_thread_snowflake = ''
+  ,file_contents = ''
+  ,content_hash = ''
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncPaused :exec
+UPDATE files_to_sync
+SET paused = @paused
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncHeading :exec
+UPDATE files_to_sync
+SET heading = @heading
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncAttachmentMode :exec
+UPDATE files_to_sync
+SET attachment_mode = @attachment_mode
+WHERE id = @file_to_sync_id
+;
+
+-- name: SetFileSyncThread :exec
+UPDATE files_to_sync
+SET discord_thread_snowflake = @thread_id
+WHERE id = @file_to_sync_id
 ;
 
 -- name: GetGuildSyncs :many
 SELECT * FROM files_to_sync
 WHERE discord_guild_snowflake = $1
+ORDER BY discord_channel_snowflake, position, id
+;
+
+-- name: GetGuildSyncSummaries :many
+SELECT
+  fts.id
+  ,fts.file_to_sync_uri AS url
+  ,fts.discord_channel_snowflake AS channel_id
+  ,fts.last_synced_at
+  ,fts.paused
+  ,grf.github_repo_url
+  ,COUNT(fcm.id) AS chunk_count
+FROM files_to_sync fts
+  LEFT JOIN github_repo_files grf ON grf.file_to_sync_fk = fts.id
+  LEFT JOIN file_chunk_messages fcm ON fcm.files_to_sync_fk = fts.id
+WHERE fts.discord_guild_snowflake = @guild_id
+GROUP BY fts.id, grf.github_repo_url
+ORDER BY fts.discord_channel_snowflake, fts.position, fts.id
+;
+
+-- name: GetGuildSyncUrls :many
+SELECT file_to_sync_uri
+FROM files_to_sync
+WHERE discord_guild_snowflake = @guild_id
+  AND file_to_sync_uri ILIKE '%' || @search::text || '%'
+GROUP BY file_to_sync_uri
+ORDER BY MAX(id) DESC
+LIMIT @max_results
 ;
 
--- name: GetGuildChannelSync :one
+-- name: GetGuildGithubRepoUrls :many
+SELECT grf.github_repo_url
+FROM github_repo_files grf
+  JOIN files_to_sync fts ON fts.id = grf.file_to_sync_fk
+WHERE fts.discord_guild_snowflake = @guild_id
+  AND grf.github_repo_url ILIKE '%' || @search::text || '%'
+GROUP BY grf.github_repo_url
+ORDER BY MAX(grf.id) DESC
+LIMIT @max_results
+;
+
+-- name: GetGuildChannelSyncs :many
 SELECT * FROM files_to_sync
 WHERE discord_guild_snowflake = @guild_id
   AND discord_channel_snowflake = @channel_id
+ORDER BY position, id
+;
+
+-- name: GetGuildFileSync :one
+SELECT * FROM files_to_sync
+WHERE discord_guild_snowflake = @guild_id
+  AND id = @file_to_sync_id
+;
+
+-- name: GetChunkMessageSource :one
+SELECT
+  fcm.chunk_number
+  ,(SELECT COUNT(*) FROM file_chunk_messages c WHERE c.files_to_sync_fk = fts.id) AS chunk_count
+  ,fts.file_to_sync_uri AS url
+  ,fts.discord_channel_snowflake AS channel_id
+  ,fts.last_synced_at
+  ,fts.file_contents
+  ,fts.attachment_mode
+  ,grf.github_repo_url
+FROM file_chunk_messages fcm
+  JOIN files_to_sync fts ON fts.id = fcm.files_to_sync_fk
+  LEFT JOIN github_repo_files grf ON grf.file_to_sync_fk = fts.id
+WHERE fcm.discord_message_id = @message_id
+  AND fts.discord_guild_snowflake = @guild_id
 ;
 
 -- name: GetFileContentChunks :many
 SELECT
   fcm.chunk_number
   ,fcm.discord_message_id
+  ,fcm.content_hash
 FROM file_chunk_messages fcm
-JOIN files_to_sync fts ON fts.id = fcm.files_to_sync_fk
-WHERE fts.discord_channel_snowflake = @channel_id
+WHERE fcm.files_to_sync_fk = @file_to_sync_fk
+ORDER BY fcm.chunk_number
 ;
 
 -- name: AddFileContentChunks :many
-INSERT INTO file_chunk_messages (files_to_sync_fk, chunk_number, discord_message_id)
-VALUES (@files_to_sync_fk, unnest(@chunk_numbers::int[]), unnest(@discord_message_ids::varchar(20)[]))
+INSERT INTO file_chunk_messages (files_to_sync_fk, chunk_number, discord_message_id, content_hash)
+VALUES (@files_to_sync_fk, unnest(@chunk_numbers::int[]), unnest(@discord_message_ids::varchar(20)[]), unnest(@content_hashes::varchar(64)[]))
 ON CONFLICT (discord_message_id)
   DO UPDATE SET
     chunk_number = excluded.chunk_number
     ,discord_message_id = excluded.discord_message_id
+    ,content_hash = excluded.content_hash
 RETURNING *
 ;
 
+-- name: SetFileContentChunkHash :exec
+UPDATE file_chunk_messages
+SET content_hash = @content_hash
+WHERE discord_message_id = @discord_message_id
+;
+
 -- name: RemoveFileContentChunks :exec
 DELETE FROM file_chunk_messages WHERE files_to_sync_fk = @file_to_sync_fk
 ;
 
+-- name: RemoveFileContentChunkMessages :exec
+DELETE FROM file_chunk_messages WHERE discord_message_id = ANY(@discord_message_ids::varchar(20)[])
+;
+
 -- name: AddGithubRepoFile :one
 INSERT INTO github_repo_files (github_repo_url, file_to_sync_fk)
 VALUES (@github_repo_url, @file_to_sync_fk)
@@ -70,4 +225,38 @@ SELECT
 FROM github_repo_files grf
   JOIN files_to_sync fts ON fts.id = grf.file_to_sync_fk
 WHERE grf.github_repo_url = @github_repo_url
-;
\ No newline at end of file
+ORDER BY fts.discord_channel_snowflake, fts.position, fts.id
+;
+
+-- name: RemoveGithubRepoFile :exec
+DELETE FROM github_repo_files WHERE file_to_sync_fk = @file_to_sync_fk
+;
+
+-- name: RemoveChannelSync :exec
+DELETE FROM files_to_sync WHERE id = @file_to_sync_id
+;
+
+-- name: AddSyncPermission :exec
+INSERT INTO sync_permissions (discord_guild_snowflake, subject_type, discord_subject_snowflake)
+VALUES (@guild_id, @subject_type, @subject_id)
+ON CONFLICT (discord_guild_snowflake, subject_type, discord_subject_snowflake)
+  DO NOTHING
+;
+
+-- name: RemoveSyncPermission :execrows
+DELETE FROM sync_permissions
+WHERE discord_guild_snowflake = @guild_id
+  AND subject_type = @subject_type
+  AND discord_subject_snowflake = @subject_id
+;
+
+-- name: RemoveGuildSyncPermissions :exec
+DELETE FROM sync_permissions
+WHERE discord_guild_snowflake = @guild_id
+;
+
+-- name: GetGuildSyncPermissions :many
+SELECT * FROM sync_permissions
+WHERE discord_guild_snowflake = @guild_id
+ORDER BY subject_type, id
+;
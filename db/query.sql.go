@@ -7,24 +7,49 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const addChannelSync = `-- name: AddChannelSync :one
-INSERT INTO files_to_sync (file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake)
-VALUES ($1, $2, $3)
-ON CONFLICT (discord_guild_snowflake, discord_channel_snowflake)
-DO UPDATE SET file_to_sync_uri = $1
-RETURNING file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents
+INSERT INTO files_to_sync (file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, suppress_embeds, pin_messages, refresh_button, heading, attachment_mode, position)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, (
+  SELECT COALESCE(MAX(position), 0) + 1
+  FROM files_to_sync
+  WHERE discord_guild_snowflake = $2
+    AND discord_channel_snowflake = $3
+))
+ON CONFLICT (discord_guild_snowflake, discord_channel_snowflake, file_to_sync_uri)
+DO UPDATE SET suppress_embeds = $4
+  ,pin_messages = $5
+  ,refresh_button = $6
+  ,heading = $7
+  ,attachment_mode = $8
+RETURNING file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents, last_synced_at, last_attempt_at, last_error, last_http_status, content_hash, discord_thread_snowflake, suppress_embeds, paused, pin_messages, refresh_button, position, heading, attachment_mode
 `
 
 type AddChannelSyncParams struct {
 	FileToSyncUri           string
 	DiscordGuildSnowflake   string
 	DiscordChannelSnowflake string
+	SuppressEmbeds          bool
+	PinMessages             bool
+	RefreshButton           bool
+	Heading                 string
+	AttachmentMode          string
 }
 
 func (q *Queries) AddChannelSync(ctx context.Context, arg AddChannelSyncParams) (FilesToSync, error) {
-	row := q.db.QueryRow(ctx, addChannelSync, arg.FileToSyncUri, arg.DiscordGuildSnowflake, arg.DiscordChannelSnowflake)
+	row := q.db.QueryRow(ctx, addChannelSync,
+		arg.FileToSyncUri,
+		arg.DiscordGuildSnowflake,
+		arg.DiscordChannelSnowflake,
+		arg.SuppressEmbeds,
+		arg.PinMessages,
+		arg.RefreshButton,
+		arg.Heading,
+		arg.AttachmentMode,
+	)
 	var i FilesToSync
 	err := row.Scan(
 		&i.FileToSyncUri,
@@ -32,28 +57,48 @@ func (q *Queries) AddChannelSync(ctx context.Context, arg AddChannelSyncParams)
 		&i.DiscordChannelSnowflake,
 		&i.ID,
 		&i.FileContents,
+		&i.LastSyncedAt,
+		&i.LastAttemptAt,
+		&i.LastError,
+		&i.LastHttpStatus,
+		&i.ContentHash,
+		&i.DiscordThreadSnowflake,
+		&i.SuppressEmbeds,
+		&i.Paused,
+		&i.PinMessages,
+		&i.RefreshButton,
+		&i.Position,
+		&i.Heading,
+		&i.AttachmentMode,
 	)
 	return i, err
 }
 
 const addFileContentChunks = `-- name: AddFileContentChunks :many
-INSERT INTO file_chunk_messages (files_to_sync_fk, chunk_number, discord_message_id)
-VALUES ($1, unnest($2::int[]), unnest($3::varchar(20)[]))
+INSERT INTO file_chunk_messages (files_to_sync_fk, chunk_number, discord_message_id, content_hash)
+VALUES ($1, unnest($2::int[]), unnest($3::varchar(20)[]), unnest($4::varchar(64)[]))
 ON CONFLICT (discord_message_id)
   DO UPDATE SET
     chunk_number = excluded.chunk_number
     ,discord_message_id = excluded.discord_message_id
-RETURNING id, files_to_sync_fk, chunk_number, discord_message_id
+    ,content_hash = excluded.content_hash
+RETURNING id, files_to_sync_fk, chunk_number, discord_message_id, content_hash
 `
 
 type AddFileContentChunksParams struct {
 	FilesToSyncFk     int64
 	ChunkNumbers      []int32
 	DiscordMessageIds []string
+	ContentHashes     []string
 }
 
 func (q *Queries) AddFileContentChunks(ctx context.Context, arg AddFileContentChunksParams) ([]FileChunkMessage, error) {
-	rows, err := q.db.Query(ctx, addFileContentChunks, arg.FilesToSyncFk, arg.ChunkNumbers, arg.DiscordMessageIds)
+	rows, err := q.db.Query(ctx, addFileContentChunks,
+		arg.FilesToSyncFk,
+		arg.ChunkNumbers,
+		arg.DiscordMessageIds,
+		arg.ContentHashes,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +111,7 @@ func (q *Queries) AddFileContentChunks(ctx context.Context, arg AddFileContentCh
 			&i.FilesToSyncFk,
 			&i.ChunkNumber,
 			&i.DiscordMessageID,
+			&i.ContentHash,
 		); err != nil {
 			return nil, err
 		}
@@ -97,21 +143,69 @@ func (q *Queries) AddGithubRepoFile(ctx context.Context, arg AddGithubRepoFilePa
 	return i, err
 }
 
-const getChannelSync = `-- name: GetChannelSync :one
-SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents
-FROM files_to_sync
-WHERE file_to_sync_uri = $1
+const addSyncPermission = `-- name: AddSyncPermission :exec
+INSERT INTO sync_permissions (discord_guild_snowflake, subject_type, discord_subject_snowflake)
+VALUES ($1, $2, $3)
+ON CONFLICT (discord_guild_snowflake, subject_type, discord_subject_snowflake)
+  DO NOTHING
 `
 
-func (q *Queries) GetChannelSync(ctx context.Context, fileUri string) (FilesToSync, error) {
-	row := q.db.QueryRow(ctx, getChannelSync, fileUri)
-	var i FilesToSync
+type AddSyncPermissionParams struct {
+	GuildID     string
+	SubjectType string
+	SubjectID   string
+}
+
+func (q *Queries) AddSyncPermission(ctx context.Context, arg AddSyncPermissionParams) error {
+	_, err := q.db.Exec(ctx, addSyncPermission, arg.GuildID, arg.SubjectType, arg.SubjectID)
+	return err
+}
+
+const getChunkMessageSource = `-- name: GetChunkMessageSource :one
+SELECT
+  fcm.chunk_number
+  ,(SELECT COUNT(*) FROM file_chunk_messages c WHERE c.files_to_sync_fk = fts.id) AS chunk_count
+  ,fts.file_to_sync_uri AS url
+  ,fts.discord_channel_snowflake AS channel_id
+  ,fts.last_synced_at
+  ,fts.file_contents
+  ,fts.attachment_mode
+  ,grf.github_repo_url
+FROM file_chunk_messages fcm
+  JOIN files_to_sync fts ON fts.id = fcm.files_to_sync_fk
+  LEFT JOIN github_repo_files grf ON grf.file_to_sync_fk = fts.id
+WHERE fcm.discord_message_id = $1
+  AND fts.discord_guild_snowflake = $2
+`
+
+type GetChunkMessageSourceParams struct {
+	MessageID string
+	GuildID   string
+}
+
+type GetChunkMessageSourceRow struct {
+	ChunkNumber    int32
+	ChunkCount     int64
+	Url            string
+	ChannelID      string
+	LastSyncedAt   pgtype.Timestamptz
+	FileContents   string
+	AttachmentMode string
+	GithubRepoUrl  pgtype.Text
+}
+
+func (q *Queries) GetChunkMessageSource(ctx context.Context, arg GetChunkMessageSourceParams) (GetChunkMessageSourceRow, error) {
+	row := q.db.QueryRow(ctx, getChunkMessageSource, arg.MessageID, arg.GuildID)
+	var i GetChunkMessageSourceRow
 	err := row.Scan(
-		&i.FileToSyncUri,
-		&i.DiscordGuildSnowflake,
-		&i.DiscordChannelSnowflake,
-		&i.ID,
+		&i.ChunkNumber,
+		&i.ChunkCount,
+		&i.Url,
+		&i.ChannelID,
+		&i.LastSyncedAt,
 		&i.FileContents,
+		&i.AttachmentMode,
+		&i.GithubRepoUrl,
 	)
 	return i, err
 }
@@ -120,18 +214,20 @@ const getFileContentChunks = `-- name: GetFileContentChunks :many
 SELECT
   fcm.chunk_number
   ,fcm.discord_message_id
+  ,fcm.content_hash
 FROM file_chunk_messages fcm
-JOIN files_to_sync fts ON fts.id = fcm.files_to_sync_fk
-WHERE fts.discord_channel_snowflake = $1
+WHERE fcm.files_to_sync_fk = $1
+ORDER BY fcm.chunk_number
 `
 
 type GetFileContentChunksRow struct {
 	ChunkNumber      int32
 	DiscordMessageID string
+	ContentHash      string
 }
 
-func (q *Queries) GetFileContentChunks(ctx context.Context, channelID string) ([]GetFileContentChunksRow, error) {
-	rows, err := q.db.Query(ctx, getFileContentChunks, channelID)
+func (q *Queries) GetFileContentChunks(ctx context.Context, fileToSyncFk int64) ([]GetFileContentChunksRow, error) {
+	rows, err := q.db.Query(ctx, getFileContentChunks, fileToSyncFk)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +235,7 @@ func (q *Queries) GetFileContentChunks(ctx context.Context, channelID string) ([
 	var items []GetFileContentChunksRow
 	for rows.Next() {
 		var i GetFileContentChunksRow
-		if err := rows.Scan(&i.ChunkNumber, &i.DiscordMessageID); err != nil {
+		if err := rows.Scan(&i.ChunkNumber, &i.DiscordMessageID, &i.ContentHash); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -160,6 +256,7 @@ SELECT
 FROM github_repo_files grf
   JOIN files_to_sync fts ON fts.id = grf.file_to_sync_fk
 WHERE grf.github_repo_url = $1
+ORDER BY fts.discord_channel_snowflake, fts.position, fts.id
 `
 
 type GetGithubRepoSyncFilesRow struct {
@@ -196,19 +293,70 @@ func (q *Queries) GetGithubRepoSyncFiles(ctx context.Context, githubRepoUrl stri
 	return items, nil
 }
 
-const getGuildChannelSync = `-- name: GetGuildChannelSync :one
-SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents FROM files_to_sync
+const getGuildChannelSyncs = `-- name: GetGuildChannelSyncs :many
+SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents, last_synced_at, last_attempt_at, last_error, last_http_status, content_hash, discord_thread_snowflake, suppress_embeds, paused, pin_messages, refresh_button, position, heading, attachment_mode FROM files_to_sync
 WHERE discord_guild_snowflake = $1
   AND discord_channel_snowflake = $2
+ORDER BY position, id
 `
 
-type GetGuildChannelSyncParams struct {
+type GetGuildChannelSyncsParams struct {
 	GuildID   string
 	ChannelID string
 }
 
-func (q *Queries) GetGuildChannelSync(ctx context.Context, arg GetGuildChannelSyncParams) (FilesToSync, error) {
-	row := q.db.QueryRow(ctx, getGuildChannelSync, arg.GuildID, arg.ChannelID)
+func (q *Queries) GetGuildChannelSyncs(ctx context.Context, arg GetGuildChannelSyncsParams) ([]FilesToSync, error) {
+	rows, err := q.db.Query(ctx, getGuildChannelSyncs, arg.GuildID, arg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilesToSync
+	for rows.Next() {
+		var i FilesToSync
+		if err := rows.Scan(
+			&i.FileToSyncUri,
+			&i.DiscordGuildSnowflake,
+			&i.DiscordChannelSnowflake,
+			&i.ID,
+			&i.FileContents,
+			&i.LastSyncedAt,
+			&i.LastAttemptAt,
+			&i.LastError,
+			&i.LastHttpStatus,
+			&i.ContentHash,
+			&i.DiscordThreadSnowflake,
+			&i.SuppressEmbeds,
+			&i.Paused,
+			&i.PinMessages,
+			&i.RefreshButton,
+			&i.Position,
+			&i.Heading,
+			&i.AttachmentMode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildFileSync = `-- name: GetGuildFileSync :one
+SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents, last_synced_at, last_attempt_at, last_error, last_http_status, content_hash, discord_thread_snowflake, suppress_embeds, paused, pin_messages, refresh_button, position, heading, attachment_mode FROM files_to_sync
+WHERE discord_guild_snowflake = $1
+  AND id = $2
+`
+
+type GetGuildFileSyncParams struct {
+	GuildID      string
+	FileToSyncID int64
+}
+
+func (q *Queries) GetGuildFileSync(ctx context.Context, arg GetGuildFileSyncParams) (FilesToSync, error) {
+	row := q.db.QueryRow(ctx, getGuildFileSync, arg.GuildID, arg.FileToSyncID)
 	var i FilesToSync
 	err := row.Scan(
 		&i.FileToSyncUri,
@@ -216,13 +364,186 @@ func (q *Queries) GetGuildChannelSync(ctx context.Context, arg GetGuildChannelSy
 		&i.DiscordChannelSnowflake,
 		&i.ID,
 		&i.FileContents,
+		&i.LastSyncedAt,
+		&i.LastAttemptAt,
+		&i.LastError,
+		&i.LastHttpStatus,
+		&i.ContentHash,
+		&i.DiscordThreadSnowflake,
+		&i.SuppressEmbeds,
+		&i.Paused,
+		&i.PinMessages,
+		&i.RefreshButton,
+		&i.Position,
+		&i.Heading,
+		&i.AttachmentMode,
 	)
 	return i, err
 }
 
+const getGuildGithubRepoUrls = `-- name: GetGuildGithubRepoUrls :many
+SELECT grf.github_repo_url
+FROM github_repo_files grf
+  JOIN files_to_sync fts ON fts.id = grf.file_to_sync_fk
+WHERE fts.discord_guild_snowflake = $1
+  AND grf.github_repo_url ILIKE '%' || $2::text || '%'
+GROUP BY grf.github_repo_url
+ORDER BY MAX(grf.id) DESC
+LIMIT $3
+`
+
+type GetGuildGithubRepoUrlsParams struct {
+	GuildID    string
+	Search     string
+	MaxResults int32
+}
+
+func (q *Queries) GetGuildGithubRepoUrls(ctx context.Context, arg GetGuildGithubRepoUrlsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getGuildGithubRepoUrls, arg.GuildID, arg.Search, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var github_repo_url string
+		if err := rows.Scan(&github_repo_url); err != nil {
+			return nil, err
+		}
+		items = append(items, github_repo_url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildSyncPermissions = `-- name: GetGuildSyncPermissions :many
+SELECT id, discord_guild_snowflake, subject_type, discord_subject_snowflake FROM sync_permissions
+WHERE discord_guild_snowflake = $1
+ORDER BY subject_type, id
+`
+
+func (q *Queries) GetGuildSyncPermissions(ctx context.Context, guildID string) ([]SyncPermission, error) {
+	rows, err := q.db.Query(ctx, getGuildSyncPermissions, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SyncPermission
+	for rows.Next() {
+		var i SyncPermission
+		if err := rows.Scan(
+			&i.ID,
+			&i.DiscordGuildSnowflake,
+			&i.SubjectType,
+			&i.DiscordSubjectSnowflake,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildSyncSummaries = `-- name: GetGuildSyncSummaries :many
+SELECT
+  fts.id
+  ,fts.file_to_sync_uri AS url
+  ,fts.discord_channel_snowflake AS channel_id
+  ,fts.last_synced_at
+  ,fts.paused
+  ,grf.github_repo_url
+  ,COUNT(fcm.id) AS chunk_count
+FROM files_to_sync fts
+  LEFT JOIN github_repo_files grf ON grf.file_to_sync_fk = fts.id
+  LEFT JOIN file_chunk_messages fcm ON fcm.files_to_sync_fk = fts.id
+WHERE fts.discord_guild_snowflake = $1
+GROUP BY fts.id, grf.github_repo_url
+ORDER BY fts.discord_channel_snowflake, fts.position, fts.id
+`
+
+type GetGuildSyncSummariesRow struct {
+	ID            int64
+	Url           string
+	ChannelID     string
+	LastSyncedAt  pgtype.Timestamptz
+	Paused        bool
+	GithubRepoUrl pgtype.Text
+	ChunkCount    int64
+}
+
+func (q *Queries) GetGuildSyncSummaries(ctx context.Context, guildID string) ([]GetGuildSyncSummariesRow, error) {
+	rows, err := q.db.Query(ctx, getGuildSyncSummaries, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetGuildSyncSummariesRow
+	for rows.Next() {
+		var i GetGuildSyncSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.ChannelID,
+			&i.LastSyncedAt,
+			&i.Paused,
+			&i.GithubRepoUrl,
+			&i.ChunkCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildSyncUrls = `-- name: GetGuildSyncUrls :many
+SELECT file_to_sync_uri
+FROM files_to_sync
+WHERE discord_guild_snowflake = $1
+  AND file_to_sync_uri ILIKE '%' || $2::text || '%'
+GROUP BY file_to_sync_uri
+ORDER BY MAX(id) DESC
+LIMIT $3
+`
+
+type GetGuildSyncUrlsParams struct {
+	GuildID    string
+	Search     string
+	MaxResults int32
+}
+
+func (q *Queries) GetGuildSyncUrls(ctx context.Context, arg GetGuildSyncUrlsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getGuildSyncUrls, arg.GuildID, arg.Search, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var file_to_sync_uri string
+		if err := rows.Scan(&file_to_sync_uri); err != nil {
+			return nil, err
+		}
+		items = append(items, file_to_sync_uri)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getGuildSyncs = `-- name: GetGuildSyncs :many
-SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents FROM files_to_sync
+SELECT file_to_sync_uri, discord_guild_snowflake, discord_channel_snowflake, id, file_contents, last_synced_at, last_attempt_at, last_error, last_http_status, content_hash, discord_thread_snowflake, suppress_embeds, paused, pin_messages, refresh_button, position, heading, attachment_mode FROM files_to_sync
 WHERE discord_guild_snowflake = $1
+ORDER BY discord_channel_snowflake, position, id
 `
 
 func (q *Queries) GetGuildSyncs(ctx context.Context, discordGuildSnowflake string) ([]FilesToSync, error) {
@@ -240,6 +561,19 @@ func (q *Queries) GetGuildSyncs(ctx context.Context, discordGuildSnowflake strin
 			&i.DiscordChannelSnowflake,
 			&i.ID,
 			&i.FileContents,
+			&i.LastSyncedAt,
+			&i.LastAttemptAt,
+			&i.LastError,
+			&i.LastHttpStatus,
+			&i.ContentHash,
+			&i.DiscordThreadSnowflake,
+			&i.SuppressEmbeds,
+			&i.Paused,
+			&i.PinMessages,
+			&i.RefreshButton,
+			&i.Position,
+			&i.Heading,
+			&i.AttachmentMode,
 		); err != nil {
 			return nil, err
 		}
@@ -251,6 +585,48 @@ func (q *Queries) GetGuildSyncs(ctx context.Context, discordGuildSnowflake strin
 	return items, nil
 }
 
+const moveChannelSync = `-- name: MoveChannelSync :exec
+UPDATE files_to_sync
+SET discord_channel_snowflake = $1
+  ,position = (
+    SELECT COALESCE(MAX(position), 0) + 1
+    FROM files_to_sync
+    WHERE discord_channel_snowflake = $1
+  )
+  ,discord_thread_snowflake = ''
+  ,file_contents = ''
+  ,content_hash = ''
+WHERE id = $2
+`
+
+type MoveChannelSyncParams struct {
+	NewChannelID string
+	FileToSyncID int64
+}
+
+func (q *Queries) MoveChannelSync(ctx context.Context, arg MoveChannelSyncParams) error {
+	_, err := q.db.Exec(ctx, moveChannelSync, arg.NewChannelID, arg.FileToSyncID)
+	return err
+}
+
+const removeChannelSync = `-- name: RemoveChannelSync :exec
+DELETE FROM files_to_sync WHERE id = $1
+`
+
+func (q *Queries) RemoveChannelSync(ctx context.Context, fileToSyncID int64) error {
+	_, err := q.db.Exec(ctx, removeChannelSync, fileToSyncID)
+	return err
+}
+
+const removeFileContentChunkMessages = `-- name: RemoveFileContentChunkMessages :exec
+DELETE FROM file_chunk_messages WHERE discord_message_id = ANY($1::varchar(20)[])
+`
+
+func (q *Queries) RemoveFileContentChunkMessages(ctx context.Context, discordMessageIds []string) error {
+	_, err := q.db.Exec(ctx, removeFileContentChunkMessages, discordMessageIds)
+	return err
+}
+
 const removeFileContentChunks = `-- name: RemoveFileContentChunks :exec
 DELETE FROM file_chunk_messages WHERE files_to_sync_fk = $1
 `
@@ -260,18 +636,234 @@ func (q *Queries) RemoveFileContentChunks(ctx context.Context, fileToSyncFk int6
 	return err
 }
 
+const removeGithubRepoFile = `-- name: RemoveGithubRepoFile :exec
+DELETE FROM github_repo_files WHERE file_to_sync_fk = $1
+`
+
+func (q *Queries) RemoveGithubRepoFile(ctx context.Context, fileToSyncFk int64) error {
+	_, err := q.db.Exec(ctx, removeGithubRepoFile, fileToSyncFk)
+	return err
+}
+
+const removeGuildSyncPermissions = `-- name: RemoveGuildSyncPermissions :exec
+DELETE FROM sync_permissions
+WHERE discord_guild_snowflake = $1
+`
+
+func (q *Queries) RemoveGuildSyncPermissions(ctx context.Context, guildID string) error {
+	_, err := q.db.Exec(ctx, removeGuildSyncPermissions, guildID)
+	return err
+}
+
+const removeSyncPermission = `-- name: RemoveSyncPermission :execrows
+DELETE FROM sync_permissions
+WHERE discord_guild_snowflake = $1
+  AND subject_type = $2
+  AND discord_subject_snowflake = $3
+`
+
+type RemoveSyncPermissionParams struct {
+	GuildID     string
+	SubjectType string
+	SubjectID   string
+}
+
+func (q *Queries) RemoveSyncPermission(ctx context.Context, arg RemoveSyncPermissionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeSyncPermission, arg.GuildID, arg.SubjectType, arg.SubjectID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setFileContentChunkHash = `-- name: SetFileContentChunkHash :exec
+UPDATE file_chunk_messages
+SET content_hash = $1
+WHERE discord_message_id = $2
+`
+
+type SetFileContentChunkHashParams struct {
+	ContentHash      string
+	DiscordMessageID string
+}
+
+func (q *Queries) SetFileContentChunkHash(ctx context.Context, arg SetFileContentChunkHashParams) error {
+	_, err := q.db.Exec(ctx, setFileContentChunkHash, arg.ContentHash, arg.DiscordMessageID)
+	return err
+}
+
+const setFileSyncAttachmentMode = `-- name: SetFileSyncAttachmentMode :exec
+UPDATE files_to_sync
+SET attachment_mode = $1
+WHERE id = $2
+`
+
+type SetFileSyncAttachmentModeParams struct {
+	AttachmentMode string
+	FileToSyncID   int64
+}
+
+func (q *Queries) SetFileSyncAttachmentMode(ctx context.Context, arg SetFileSyncAttachmentModeParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncAttachmentMode, arg.AttachmentMode, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncAttempt = `-- name: SetFileSyncAttempt :exec
+UPDATE files_to_sync
+SET last_attempt_at = now()
+  ,last_error = $1
+  ,last_http_status = $2
+WHERE id = $3
+`
+
+type SetFileSyncAttemptParams struct {
+	LastError      string
+	LastHttpStatus pgtype.Int4
+	FileToSyncID   int64
+}
+
+func (q *Queries) SetFileSyncAttempt(ctx context.Context, arg SetFileSyncAttemptParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncAttempt, arg.LastError, arg.LastHttpStatus, arg.FileToSyncID)
+	return err
+}
+
 const setFileSyncContents = `-- name: SetFileSyncContents :exec
 UPDATE files_to_sync
 SET file_contents = $1
-WHERE discord_channel_snowflake = $2
+  ,content_hash = $2
+WHERE id = $3
 `
 
 type SetFileSyncContentsParams struct {
 	FileContents string
-	ChannelID    string
+	ContentHash  string
+	FileToSyncID int64
 }
 
 func (q *Queries) SetFileSyncContents(ctx context.Context, arg SetFileSyncContentsParams) error {
-	_, err := q.db.Exec(ctx, setFileSyncContents, arg.FileContents, arg.ChannelID)
+	_, err := q.db.Exec(ctx, setFileSyncContents, arg.FileContents, arg.ContentHash, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncHeading = `-- name: SetFileSyncHeading :exec
+UPDATE files_to_sync
+SET heading = $1
+WHERE id = $2
+`
+
+type SetFileSyncHeadingParams struct {
+	Heading      string
+	FileToSyncID int64
+}
+
+func (q *Queries) SetFileSyncHeading(ctx context.Context, arg SetFileSyncHeadingParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncHeading, arg.Heading, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncPaused = `-- name: SetFileSyncPaused :exec
+UPDATE files_to_sync
+SET paused = $1
+WHERE id = $2
+`
+
+type SetFileSyncPausedParams struct {
+	Paused       bool
+	FileToSyncID int64
+}
+
+func (q *Queries) SetFileSyncPaused(ctx context.Context, arg SetFileSyncPausedParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncPaused, arg.Paused, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncPinMessages = `-- name: SetFileSyncPinMessages :exec
+UPDATE files_to_sync
+SET pin_messages = $1
+WHERE id = $2
+`
+
+type SetFileSyncPinMessagesParams struct {
+	PinMessages  bool
+	FileToSyncID int64
+}
+
+func (q *Queries) SetFileSyncPinMessages(ctx context.Context, arg SetFileSyncPinMessagesParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncPinMessages, arg.PinMessages, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncRefreshButton = `-- name: SetFileSyncRefreshButton :exec
+UPDATE files_to_sync
+SET refresh_button = $1
+WHERE id = $2
+`
+
+type SetFileSyncRefreshButtonParams struct {
+	RefreshButton bool
+	FileToSyncID  int64
+}
+
+func (q *Queries) SetFileSyncRefreshButton(ctx context.Context, arg SetFileSyncRefreshButtonParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncRefreshButton, arg.RefreshButton, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncSuppressEmbeds = `-- name: SetFileSyncSuppressEmbeds :exec
+UPDATE files_to_sync
+SET suppress_embeds = $1
+WHERE id = $2
+`
+
+type SetFileSyncSuppressEmbedsParams struct {
+	SuppressEmbeds bool
+	FileToSyncID   int64
+}
+
+func (q *Queries) SetFileSyncSuppressEmbeds(ctx context.Context, arg SetFileSyncSuppressEmbedsParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncSuppressEmbeds, arg.SuppressEmbeds, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncThread = `-- name: SetFileSyncThread :exec
+UPDATE files_to_sync
+SET discord_thread_snowflake = $1
+WHERE id = $2
+`
+
+type SetFileSyncThreadParams struct {
+	ThreadID     string
+	FileToSyncID int64
+}
+
+func (q *Queries) SetFileSyncThread(ctx context.Context, arg SetFileSyncThreadParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncThread, arg.ThreadID, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncUri = `-- name: SetFileSyncUri :exec
+UPDATE files_to_sync
+SET file_to_sync_uri = $1
+WHERE id = $2
+`
+
+type SetFileSyncUriParams struct {
+	FileUri      string
+	FileToSyncID int64
+}
+
+func (q *Queries) SetFileSyncUri(ctx context.Context, arg SetFileSyncUriParams) error {
+	_, err := q.db.Exec(ctx, setFileSyncUri, arg.FileUri, arg.FileToSyncID)
+	return err
+}
+
+const setFileSyncedAt = `-- name: SetFileSyncedAt :exec
+UPDATE files_to_sync
+SET last_synced_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) SetFileSyncedAt(ctx context.Context, fileToSyncID int64) error {
+	_, err := q.db.Exec(ctx, setFileSyncedAt, fileToSyncID)
 	return err
 }
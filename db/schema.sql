@@ -21,7 +21,8 @@ CREATE TABLE public.file_chunk_messages (
     id bigint NOT NULL,
     files_to_sync_fk bigint NOT NULL,
     chunk_number integer NOT NULL,
-    discord_message_id character varying(20) NOT NULL
+    discord_message_id character varying(20) NOT NULL,
+    content_hash character varying(64) DEFAULT ''::character varying NOT NULL
 );
 
 
@@ -53,7 +54,21 @@ CREATE TABLE public.files_to_sync (
     discord_guild_snowflake character varying(20) NOT NULL,
     discord_channel_snowflake character varying(20) NOT NULL,
     id bigint NOT NULL,
-    file_contents text DEFAULT ''::text NOT NULL
+    file_contents text DEFAULT ''::text NOT NULL,
+    last_synced_at timestamp with time zone,
+    last_attempt_at timestamp with time zone,
+    last_error text DEFAULT ''::text NOT NULL,
+    last_http_status integer,
+    content_hash character varying(64) DEFAULT ''::character varying NOT NULL,
+    discord_thread_snowflake character varying(20) DEFAULT ''::character varying NOT NULL,
+    suppress_embeds boolean DEFAULT false NOT NULL,
+    paused boolean DEFAULT false NOT NULL,
+    pin_messages boolean DEFAULT false NOT NULL,
+    refresh_button boolean DEFAULT false NOT NULL,
+    "position" integer DEFAULT 0 NOT NULL,
+    heading character varying(48) DEFAULT ''::character varying NOT NULL,
+    attachment_mode character varying(10) DEFAULT 'auto'::character varying NOT NULL,
+    CONSTRAINT files_to_sync_attachment_mode_check CHECK (((attachment_mode)::text = ANY ((ARRAY['auto'::character varying, 'messages'::character varying, 'attachment'::character varying])::text[])))
 );
 
 
@@ -115,6 +130,38 @@ CREATE TABLE public.schema_migrations (
 );
 
 
+--
+-- Name: sync_permissions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.sync_permissions (
+    id bigint NOT NULL,
+    discord_guild_snowflake character varying(20) NOT NULL,
+    subject_type character varying(8) NOT NULL,
+    discord_subject_snowflake character varying(20) NOT NULL,
+    CONSTRAINT sync_permissions_subject_type_check CHECK (((subject_type)::text = ANY ((ARRAY['role'::character varying, 'user'::character varying])::text[])))
+);
+
+
+--
+-- Name: sync_permissions_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.sync_permissions_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: sync_permissions_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.sync_permissions_id_seq OWNED BY public.sync_permissions.id;
+
+
 --
 -- Name: file_chunk_messages id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -136,6 +183,13 @@ ALTER TABLE ONLY public.files_to_sync ALTER COLUMN id SET DEFAULT nextval('publi
 ALTER TABLE ONLY public.github_repo_files ALTER COLUMN id SET DEFAULT nextval('public.github_repo_files_id_seq'::regclass);
 
 
+--
+-- Name: sync_permissions id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sync_permissions ALTER COLUMN id SET DEFAULT nextval('public.sync_permissions_id_seq'::regclass);
+
+
 --
 -- Name: file_chunk_messages file_chunk_messages_chunk_number_discord_message_id_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -169,11 +223,11 @@ ALTER TABLE ONLY public.files_to_sync
 
 
 --
--- Name: files_to_sync files_to_sync_unique_channel; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: files_to_sync files_to_sync_unique_channel_file; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
 ALTER TABLE ONLY public.files_to_sync
-    ADD CONSTRAINT files_to_sync_unique_channel UNIQUE (discord_guild_snowflake, discord_channel_snowflake);
+    ADD CONSTRAINT files_to_sync_unique_channel_file UNIQUE (discord_guild_snowflake, discord_channel_snowflake, file_to_sync_uri);
 
 
 --
@@ -208,6 +262,22 @@ ALTER TABLE ONLY public.schema_migrations
     ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
 
 
+--
+-- Name: sync_permissions sync_permissions_discord_guild_snowflake_subject_type_disco_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sync_permissions
+    ADD CONSTRAINT sync_permissions_discord_guild_snowflake_subject_type_disco_key UNIQUE (discord_guild_snowflake, subject_type, discord_subject_snowflake);
+
+
+--
+-- Name: sync_permissions sync_permissions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sync_permissions
+    ADD CONSTRAINT sync_permissions_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: file_chunk_messages file_chunk_messages_files_to_sync_fk_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -236,4 +306,15 @@ ALTER TABLE ONLY public.github_repo_files
 INSERT INTO public.schema_migrations (version) VALUES
     ('20240808225441'),
     ('20240811003207'),
-    ('20240814073257');
+    ('20240814073257'),
+    ('20261017090000'),
+    ('20261017093000'),
+    ('20261017100000'),
+    ('20261017103000'),
+    ('20261017110000'),
+    ('20261017113000'),
+    ('20261017120000'),
+    ('20261017123000'),
+    ('20261017130000'),
+    ('20261017133000'),
+    ('20261017140000');
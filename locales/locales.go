@@ -0,0 +1,65 @@
+// Package locales translates the bot's text into the languages discord supports.
+//
+// Each catalog is a JSON file named after a discord locale (e.g. de.json) that maps English text,
+// as written in the source, to its translation. Text without a translation is left in English.
+package locales
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+//go:embed *.json
+var catalogFiles embed.FS
+
+// Translations of English text, by locale.
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[discordgo.Locale]map[string]string {
+	entries, err := catalogFiles.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+
+	catalogs := make(map[discordgo.Locale]map[string]string, len(entries))
+	for _, entry := range entries {
+		contents, err := catalogFiles.ReadFile(entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(contents, &catalog); err != nil {
+			panic("locales: invalid catalog " + entry.Name() + ": " + err.Error())
+		}
+		locale := discordgo.Locale(strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+		catalogs[locale] = catalog
+	}
+	return catalogs
+}
+
+// Translates text into the given locale, or returns it unchanged if there is no translation.
+func Translate(locale discordgo.Locale, text string) string {
+	if translated, ok := catalogs[locale][text]; ok && translated != "" {
+		return translated
+	}
+	return text
+}
+
+// Gets every translation of text, for use as a command's name or description localizations.
+// Returns nil if text has no translations.
+func Localizations(text string) *map[discordgo.Locale]string {
+	localizations := make(map[discordgo.Locale]string)
+	for locale, catalog := range catalogs {
+		if translated, ok := catalog[text]; ok && translated != "" {
+			localizations[locale] = translated
+		}
+	}
+	if len(localizations) == 0 {
+		return nil
+	}
+	return &localizations
+}
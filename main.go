@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -81,6 +83,21 @@ func main() {
 		isDebug = false
 	}
 
+	// Read in COMMAND_REGISTRATION env variable, defaulting to per guild registration.
+	registration := commands.RegistrationConfig{
+		Mode:       commands.RegistrationModeGuild,
+		DevGuildId: os.Getenv("DISCORD_DEV_GUILD_ID"),
+	}
+	if os.Getenv("COMMAND_REGISTRATION") != "" {
+		registration.Mode, err = commands.ParseRegistrationMode(os.Getenv("COMMAND_REGISTRATION"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid COMMAND_REGISTRATION environment variable.")
+		}
+	}
+	if registration.Mode == commands.RegistrationModeDevGuild && registration.DevGuildId == "" {
+		log.Fatal().Msg("DISCORD_DEV_GUILD_ID environment variable must be set when COMMAND_REGISTRATION is dev-guild.")
+	}
+
 	// Initialize database connection pool
 	dbUser := os.Getenv("DATABASE_USER")
 	dbPass := os.Getenv("DATABASE_PASSWORD")
@@ -107,7 +124,7 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create new discord session.")
 	}
-	discord.Identify.Intents = discordgo.IntentsGuildMessages
+	discord.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages
 
 	err = discord.Open()
 	if err != nil {
@@ -118,11 +135,12 @@ func main() {
 	// Initial application context
 	appCtx := &config.AppCtx{
 		DB:             db.New(conn),
+		DBPool:         conn,
 		DiscordSession: discord,
 	}
 
 	// Register discord slash commands
-	commands.RegisterAllCommands(discord, appCtx)
+	commands.RegisterAllCommands(discord, appCtx, registration)
 
 	// Initialize webhook listener
 	go func() {
@@ -170,16 +188,31 @@ func githubWebhookHandler(appCtx config.AppCtx) func(w http.ResponseWriter, r *h
 			return
 		}
 
-		// Sync each file
+		// Sync each file, writing the response once all have run so a failure later on still fails the delivery
+		var skipped []string
+		var failed []string
 		for _, file := range files {
 			ctx := logger.WithContext(context.Background())
-			err := commands.SyncFileToDiscordMessages(ctx, appCtx, file.GuildID, file.ChannelID, file.Url, file.FileContents)
+			_, err := commands.SyncFileToDiscordMessages(ctx, appCtx, file.GuildID, file.FilesToSyncID, nil)
+			if errors.Is(err, commands.ErrSyncPaused) {
+				skipped = append(skipped, fmt.Sprintf("Skipped paused sync of %s in channel %s.", file.Url, file.ChannelID))
+				continue
+			}
 			if err != nil {
 				logger.Error().Err(err).Msg("")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				failed = append(failed, fmt.Sprintf("Failed to sync %s in channel %s: %s", file.Url, file.ChannelID, err))
 			}
 		}
 
-		log.Info().Interface("request_body", pushEvent).Msg("Connection processed.")
+		log.Info().Interface("request_body", pushEvent).Int("syncs_skipped", len(skipped)).Int("syncs_failed", len(failed)).Msg("Connection processed.")
+
+		body := strings.Join(append(skipped, failed...), "\n")
+		if len(failed) > 0 {
+			http.Error(w, body, http.StatusInternalServerError)
+			return
+		}
+		if body != "" {
+			fmt.Fprintln(w, body)
+		}
 	}
 }